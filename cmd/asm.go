@@ -190,6 +190,34 @@ func writeAsm(w *bytes.Buffer, text []byte, protos []proto.Proto) error {
 		case proto.INS_TRY:
 			steps := getUint32(&pc, text)
 			fmt.Fprintf(w, "TRY %d", pc+int(steps))
+		case proto.INS_CALL_FINALLY:
+			steps := getUint32(&pc, text)
+			fmt.Fprintf(w, "CALL_FINALLY %d", pc+int(steps))
+		case proto.INS_END_FINALLY:
+			fmt.Fprintf(w, "END_FINALLY")
+		case proto.INS_LOAD_HOST:
+			fmt.Fprintf(w, "LOAD_HOST %d", getUint32(&pc, text))
+		case proto.INS_ITER_NEW:
+			fmt.Fprintf(w, "ITER_NEW")
+		case proto.INS_ITER_NEXT:
+			fmt.Fprintf(w, "ITER_NEXT %d", getUint32(&pc, text))
+		case proto.INS_ITER_UNPACK:
+			idx := getUint32(&pc, text)
+			steps := getUint32(&pc, text)
+			fmt.Fprintf(w, "ITER_UNPACK %d %d", idx, pc+int(steps))
+		case proto.INS_NEW_OBJECT:
+			argCnt := text[pc]
+			pc++
+			fmt.Fprintf(w, "NEW_OBJECT %d", argCnt)
+		case proto.INS_INHERIT:
+			fmt.Fprintf(w, "INHERIT")
+		case proto.INS_CALL_SUPER:
+			fmt.Fprintf(w, "CALL_SUPER")
+		case proto.INS_INSTANCEOF:
+			fmt.Fprintf(w, "INSTANCEOF")
+		case proto.INS_CONCAT:
+			cnt := getUint32(&pc, text)
+			fmt.Fprintf(w, "CONCAT %d", cnt)
 		default:
 			return fmt.Errorf("invalid instruction code: %d", instruction)
 		}
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gscript/compiler"
+	"gscript/compiler/lexer"
+	"gscript/compiler/token"
+	"gscript/proto"
+	"gscript/std"
+	"gscript/vm"
+	"gscript/vm/types"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/peterh/liner"
+	"github.com/spf13/cobra"
+)
+
+const (
+	replFile       = "<stdin>"
+	replPrompt     = ">>> "
+	replContPrompt = "... "
+	historyFile    = ".gsc_history"
+)
+
+var replCmd = &cobra.Command{
+	Use:                   "repl",
+	Short:                 "Start an interactive session. Usage: gsc repl",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		stdlibs, err := std.ReadProtos()
+		if err != nil {
+			exit(err)
+		}
+		os.Exit(newRepl(stdlibs).loop())
+	},
+}
+
+type repl struct {
+	session *compiler.Session
+	vm      *vm.VM
+	line    *liner.State
+}
+
+func newRepl(stdlibs []proto.Proto) *repl {
+	main := proto.Proto{FilePath: replFile, Text: []byte{proto.INS_STOP}}
+	v := vm.NewVM([]proto.Proto{main}, stdlibs)
+	// run the empty main proto, then chunks can be run
+	v.Run()
+	return &repl{
+		session: compiler.NewSession(replFile),
+		vm:      v,
+	}
+}
+
+// loop reads and executes inputs until EOF or script calls exit, returns the exit code
+func (r *repl) loop() int {
+	r.line = liner.NewLiner()
+	defer r.line.Close()
+	r.line.SetCtrlCAborts(true)
+	r.line.SetWordCompleter(r.complete)
+	r.loadHistory()
+	defer r.saveHistory()
+
+	fmt.Printf("gscript %d.%d, press Ctrl-D to exit\n", proto.VersionMajor, proto.VersionMinor)
+	for {
+		code, err := r.read()
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err != nil {
+			if err == io.EOF {
+				fmt.Println()
+			}
+			return 0
+		}
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+		r.line.AppendHistory(code)
+		if exitErr := r.exec(code); exitErr != nil {
+			return exitErr.Code
+		}
+	}
+}
+
+// read an input, lines are read until brackets are closed or an empty line is entered
+func (r *repl) read() (string, error) {
+	code, err := r.line.Prompt(replPrompt)
+	if err != nil {
+		return "", err
+	}
+	for incomplete(code) {
+		line, err := r.line.Prompt(replContPrompt)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		code += "\n" + line
+	}
+	return code, nil
+}
+
+func (r *repl) exec(code string) *vm.ExitError {
+	main, start, err := r.session.Complie([]byte(code))
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	// Ctrl-C interrupts the running code instead of the REPL
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	r.vm.SetContext(ctx)
+	rets, err := r.vm.RunChunk(main, start)
+	if err != nil {
+		r.session.Rollback()
+		var exitErr *vm.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr
+		}
+		if e, ok := err.(*vm.RuntimeError); ok && e.Uncaught {
+			fmt.Print(e.Traceback)
+		}
+		fmt.Println(err)
+		return nil
+	}
+	for _, ret := range rets {
+		if ret == nil {
+			continue
+		}
+		vm.Fprint(os.Stdout, ret)
+		fmt.Println()
+	}
+	return nil
+}
+
+// complete names in scope, and attributes of objects for input like 'obj.prefix'
+func (r *repl) complete(line string, pos int) (head string, completions []string, tail string) {
+	start := pos
+	for start > 0 && isNameChar(line[start-1]) {
+		start--
+	}
+	head, word, tail := line[:start], line[start:pos], line[pos:]
+
+	var candidates []string
+	if dot := strings.IndexByte(word, '.'); dot >= 0 {
+		obj := word[:dot]
+		head, word = head+obj+".", word[dot+1:]
+		candidates = r.attributes(obj)
+	} else {
+		candidates = r.names()
+	}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			completions = append(completions, candidate)
+		}
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+func (r *repl) names() []string {
+	var names []string
+	for keyword := range token.Keywords {
+		names = append(names, keyword)
+	}
+	for _, name := range r.session.Names() {
+		// hide internal builtin functions
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (r *repl) attributes(name string) []string {
+	idx, ok := r.session.Variable(name)
+	if !ok {
+		return nil
+	}
+	val, _ := r.vm.GlobalValue(idx)
+	obj, ok := val.(*types.Object)
+	if !ok {
+		return nil
+	}
+	var attrs []string
+	obj.ForEach(func(k, v interface{}) {
+		if k, ok := k.(string); ok {
+			attrs = append(attrs, k)
+		}
+	})
+	return attrs
+}
+
+func (r *repl) loadHistory() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	if file, err := os.Open(filepath.Join(home, historyFile)); err == nil {
+		r.line.ReadHistory(file)
+		file.Close()
+	}
+}
+
+func (r *repl) saveHistory() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	if file, err := os.Create(filepath.Join(home, historyFile)); err == nil {
+		r.line.WriteHistory(file)
+		file.Close()
+	}
+}
+
+// bytes of non-ASCII characters are treated as parts of names, which may contain unicode letters
+func isNameChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c >= utf8.RuneSelf
+}
+
+// whether brackets of code are not closed, more lines are needed
+func incomplete(code string) (more bool) {
+	defer func() {
+		// let compiler report invalid tokens
+		if r := recover(); r != nil {
+			more = false
+		}
+	}()
+	l := lexer.NewLexer(replFile, []byte(code))
+	depth := 0
+	for {
+		switch l.NextToken().Kind {
+		case token.TOKEN_EOF:
+			// template strings and raw strings quoted by ` can span lines
+			for _, e := range l.Errors() {
+				if strings.HasPrefix(e.Msg, "expect another `") {
+					return true
+				}
+			}
+			return depth > 0
+		case token.TOKEN_SEP_LBRACK, token.TOKEN_SEP_LPAREN, token.TOKEN_SEP_LCURLY, token.TOKEN_TEMPLATE_HEAD:
+			depth++
+		case token.TOKEN_SEP_RBRACK, token.TOKEN_SEP_RPAREN, token.TOKEN_SEP_RCURLY, token.TOKEN_TEMPLATE_TAIL:
+			depth--
+		}
+	}
+}
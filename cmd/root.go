@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"gscript/compiler"
+	"gscript/compiler/ast"
 	"gscript/proto"
 	"gscript/std"
 	"gscript/vm"
@@ -16,6 +17,12 @@ import (
 var (
 	Flag_Output string
 	Flag_Asm    bool
+
+	Flag_Sandbox    bool
+	Flag_AllowRead  []string
+	Flag_AllowWrite []string
+	Flag_AllowExec  bool
+	Flag_AllowEnv   bool
 )
 
 var rootCmd = &cobra.Command{
@@ -35,19 +42,27 @@ var versionCmd = &cobra.Command{
 }
 
 var runCmd = &cobra.Command{
-	Use:   "run <file>",
-	Short: "Excute script file. Usage: gsc run <file>",
+	Use:   "run [flags] <file>",
+	Short: "Excute script file. Usage: gsc run [flags] <file>",
 	Args:  cobra.MinimumNArgs(1),
 	FParseErrWhitelist: cobra.FParseErrWhitelist{
 		UnknownFlags: true,
 	},
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
-		vm, err := initVM(args[0])
+		v, err := initVM(args[0])
 		if err != nil {
 			exit(err)
 		}
-		vm.Run()
+		if Flag_Sandbox {
+			v.SetSandbox(&vm.Sandbox{
+				AllowExec:  Flag_AllowExec,
+				AllowEnv:   Flag_AllowEnv,
+				ReadRoots:  Flag_AllowRead,
+				WriteRoots: Flag_AllowWrite,
+			})
+		}
+		exitWithRuntimeError(v.Run())
 	},
 }
 
@@ -60,11 +75,11 @@ var debugCmd = &cobra.Command{
 	},
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
-		vm, err := initVM(args[0])
+		v, err := initVM(args[0])
 		if err != nil {
 			exit(err)
 		}
-		vm.Debug()
+		exitWithRuntimeError(v.Debug())
 	},
 }
 
@@ -138,7 +153,12 @@ func initVM(src string) (*vm.VM, error) {
 func init() {
 	buildCmd.Flags().StringVarP(&Flag_Output, "output", "o", "", "output file")
 	buildCmd.Flags().BoolVarP(&Flag_Asm, "asm", "a", false, "output human-readable assembly code")
-	rootCmd.AddCommand(runCmd, versionCmd, debugCmd, buildCmd)
+	runCmd.Flags().BoolVar(&Flag_Sandbox, "sandbox", false, "restrict capabilities of script, see --allow-* flags")
+	runCmd.Flags().StringSliceVar(&Flag_AllowRead, "allow-read", nil, "directories where files can be read in sandbox")
+	runCmd.Flags().StringSliceVar(&Flag_AllowWrite, "allow-write", nil, "directories where files can be read and written in sandbox")
+	runCmd.Flags().BoolVar(&Flag_AllowExec, "allow-exec", false, "allow running commands in sandbox")
+	runCmd.Flags().BoolVar(&Flag_AllowEnv, "allow-env", false, "allow modifying environment variables and working directory in sandbox")
+	rootCmd.AddCommand(runCmd, versionCmd, debugCmd, buildCmd, replCmd)
 }
 
 func Execute() {
@@ -152,6 +172,27 @@ func exit(err error) {
 	if err == nil {
 		return
 	}
+	// one error per line
+	if errs, ok := err.(ast.ErrorList); ok && len(errs) > 1 {
+		fmt.Printf("Failed: %d errors found\n%s\n", len(errs), errs.Error())
+		os.Exit(1)
+	}
 	fmt.Printf("Failed: %s\n", err.Error())
-	os.Exit(0)
+	os.Exit(1)
+}
+
+func exitWithRuntimeError(err error) {
+	switch err := err.(type) {
+	case nil:
+		return
+	case *vm.ExitError:
+		os.Exit(err.Code)
+	case *vm.RuntimeError:
+		// traceback shows callers of where it fails, e.g. script calling a std library
+		fmt.Print(err.Traceback)
+		fmt.Println(err)
+		os.Exit(1)
+	default:
+		exit(err)
+	}
 }
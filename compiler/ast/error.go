@@ -0,0 +1,92 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Error is a syntax or semantic error found when compiling source code
+type Error struct {
+	File     string
+	Line     int
+	Column   int // 0 if unknown
+	Severity Severity
+	Msg      string
+}
+
+func (e *Error) Error() string {
+	msg := e.Msg
+	if e.Severity == SeverityWarning {
+		msg = "warning: " + msg
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("[%s] %s", e.File, msg)
+	}
+	if e.Column == 0 {
+		return fmt.Sprintf("[%s:%d] %s", e.File, e.Line, msg)
+	}
+	return fmt.Sprintf("[%s:%d:%d] %s", e.File, e.Line, e.Column, msg)
+}
+
+// ErrorList is a list of diagnostics found when compiling, one per line when printed
+type ErrorList []*Error
+
+func (list *ErrorList) Add(e *Error) {
+	*list = append(*list, e)
+}
+
+// Sort sorts the list by file and position
+func (list ErrorList) Sort() {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// Err returns nil if the list contains no error of SeverityError, otherwise the list itself
+func (list ErrorList) Err() error {
+	for _, e := range list {
+		if e.Severity == SeverityError {
+			return list
+		}
+	}
+	return nil
+}
+
+func (list ErrorList) Error() string {
+	msgs := make([]string, len(list))
+	for i, e := range list {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap makes errors.As find the first *Error in the list
+func (list ErrorList) Unwrap() []error {
+	errs := make([]error, len(list))
+	for i, e := range list {
+		errs[i] = e
+	}
+	return errs
+}
@@ -15,6 +15,11 @@ type StringLiteralExp struct {
 	Value string
 }
 
+// `...${exp}...`, parts are concatenated as strings
+type TemplateExp struct {
+	Parts []Exp
+}
+
 type NumberLiteralExp struct {
 	Value interface{}
 }
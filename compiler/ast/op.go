@@ -38,6 +38,8 @@ const (
 	BINOP_LAND  // &&
 	BINOP_LOR   // ||
 	BINOP_ATTR  // []
+
+	BINOP_INSTANCEOF // instanceof
 )
 
 const (
@@ -20,6 +20,12 @@ type Lib struct {
 	Alias  string
 }
 
+// position of a node in source file
+type Pos struct {
+	Line   int
+	Column int
+}
+
 // Block or Stmt
 type BlockStmt interface{}
 
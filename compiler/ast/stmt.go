@@ -12,6 +12,7 @@ type VarDeclStmt struct {
 	Line   int
 	Lefts  []string
 	Rights []Exp
+	Const  bool // declared by const, variables can not be assigned
 }
 
 // number, map["key"] = 2,"value"
@@ -101,6 +102,7 @@ type LoopStmt struct {
 	Val      string
 	Iterator Exp
 	Block    Block
+	Const    bool // loop variables are declared by const
 }
 
 // else ==> elif(true)
@@ -114,6 +116,7 @@ type ClassStmt struct {
 	AttrName    []string
 	AttrValue   []Exp
 	Constructor *FuncLiteralExp
+	Parent      Exp // nil if class extends nothing
 }
 
 type EnumStmt struct {
@@ -134,8 +137,10 @@ type ReturnStmt struct {
 }
 
 type TryCatchStmt struct {
-	TryBlocks   []BlockStmt
-	CatchValue  string
-	CatchLine   int
-	CatchBlocks []BlockStmt
+	TryBlocks     []BlockStmt
+	HasCatch      bool
+	CatchValue    string
+	CatchLine     int
+	CatchBlocks   []BlockStmt
+	FinallyBlocks []BlockStmt
 }
@@ -1,10 +1,5 @@
 package codegen
 
-import (
-	"fmt"
-	"os"
-)
-
 type ConstTable struct {
 	Constants []interface{}
 	ConsMap   map[interface{}]uint32 // constant -> constants index
@@ -25,8 +20,7 @@ func newConstTable() *ConstTable {
 
 func (ct *ConstTable) saveEnum(name string, line int, num int64) {
 	if exists, ok := ct.enums[name]; ok {
-		fmt.Printf("[%s] enum name '%s' already defines at line %d, but redeclares at line %d", curParsingFile, name, exists.line, line)
-		os.Exit(0)
+		errorf(line, "enum name '%s' already defines at line %d", name, exists.line)
 	}
 	ct.enums[name] = enum{
 		idx:  uint32(len(ct.Constants)),
@@ -2,11 +2,9 @@ package codegen
 
 import (
 	"encoding/binary"
-	"fmt"
 	"gscript/compiler/ast"
 	"gscript/compiler/parser"
 	"gscript/proto"
-	"os"
 )
 
 type Context struct {
@@ -16,7 +14,10 @@ type Context struct {
 	ft       *FuncTable
 	classes  map[string]uint32 // class name -> FuncTable index
 
+	hostGlobals map[string]uint32 // global name -> index of host value
+
 	frame *StackFrame
+	errs  ast.ErrorList // errors of invalid statements
 }
 
 func newContext(parser *parser.Parser) *Context {
@@ -32,18 +33,105 @@ func newContext(parser *parser.Parser) *Context {
 
 func newClassTable(stmts []*ast.ClassStmt, ft *FuncTable) map[string]uint32 {
 	classes := map[string]uint32{}
-	ft.anonymousFuncs = make([]proto.AnonymousFuncProto, len(stmts))
-	for i, stmt := range stmts {
-		classes[stmt.Name] = uint32(i)
+	addClasses(classes, stmts, ft)
+	return classes
+}
+
+// constructors of classes are anonymous functions, a redefined class reuses the index of the old one
+func addClasses(classes map[string]uint32, stmts []*ast.ClassStmt, ft *FuncTable) {
+	for _, stmt := range stmts {
+		idx, ok := classes[stmt.Name]
+		if !ok {
+			idx = uint32(len(ft.anonymousFuncs))
+			classes[stmt.Name] = idx
+			ft.anonymousFuncs = append(ft.anonymousFuncs, proto.AnonymousFuncProto{})
+		}
 		info := &proto.BasicInfo{}
 		__self := stmt.Constructor
 		if __self != nil {
 			info.Parameters = __self.Parameters
 			info.VaArgs = __self.VaArgs != ""
+		} else if stmt.Parent != nil {
+			// arguments are collected and passed to constructor of parent
+			info.VaArgs = true
 		}
-		ft.anonymousFuncs[i].Info = info
+		ft.anonymousFuncs[idx] = proto.AnonymousFuncProto{Info: info, Name: stmt.Name}
 	}
-	return classes
+}
+
+// call gen to generate stmt. If stmt is invalid, the error is recorded and states changed
+// by gen are restored, so generating can go on with the next statement.
+// errors are raised with lines only, columns are filled in by the innermost statement or
+// expression at the line which contains them
+func (ctx *Context) locateError(err *ast.Error, node interface{}) {
+	if pos, ok := ctx.parser.Positions[node]; ok && err.Column == 0 && err.Line == pos.Line {
+		err.Column = pos.Column
+	}
+}
+
+// deferred by genExp to locate errors raised by exp
+func (ctx *Context) locate(exp ast.Exp) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if err, ok := r.(*ast.Error); ok {
+		ctx.locateError(err, exp)
+	}
+	panic(r)
+}
+
+func (ctx *Context) try(stmt ast.BlockStmt, gen func()) {
+	frame := ctx.frame
+	nt, block, tryLevel, tryBlocks := frame.nt, frame.bs.cur, frame.curTryLevel, frame.tryBlocks
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		err, ok := r.(*ast.Error)
+		if !ok {
+			panic(r)
+		}
+		if pos, ok := ctx.parser.Positions[stmt]; ok && err.Line == 0 {
+			// report at the statement if the error has no position
+			err.Line, err.Column = pos.Line, pos.Column
+		}
+		ctx.locateError(err, stmt)
+		ctx.errs.Add(err)
+		ctx.frame = frame
+		frame.nt, frame.bs.cur, frame.curTryLevel, frame.tryBlocks = nt, block, tryLevel, tryBlocks
+		// declare variables of the statement anyway, they should not be reported as undeclared later
+		if decl, ok := stmt.(*ast.VarDeclStmt); ok {
+			for _, name := range decl.Lefts {
+				if _, ok := nt.nameTable[name]; !ok {
+					nt.Set(name, uint32(decl.Line))
+				}
+			}
+		}
+	}()
+	gen()
+}
+
+// panic errors of invalid statements and err as ast.ErrorList if any
+func (ctx *Context) checkErrors(err interface{}, file string) {
+	if err != nil {
+		e, ok := err.(*ast.Error)
+		if !ok {
+			panic(err)
+		}
+		ctx.errs.Add(e)
+	}
+	if len(ctx.errs) == 0 {
+		return
+	}
+	errs := ctx.errs
+	ctx.errs = nil
+	for _, e := range errs {
+		e.File = file
+	}
+	errs.Sort()
+	panic(errs)
 }
 
 func (ctx *Context) pushFrame(anonymous bool, idx int) {
@@ -72,6 +160,32 @@ func (ctx *Context) writeByte(v byte) {
 	ctx.frame.text = append(ctx.frame.text, v)
 }
 
+// record source position of node for instructions generated from now on
+func (ctx *Context) setPos(node interface{}) {
+	pos, ok := ctx.parser.Positions[node]
+	if !ok {
+		return
+	}
+	ctx.setLine(uint32(pos.Line), uint32(pos.Column))
+}
+
+// same as setPos, column is zero if unknown
+func (ctx *Context) setLine(line, column uint32) {
+	frame := ctx.frame
+	info := proto.LineInfo{Pc: ctx.textSize(), Line: line, Column: column}
+	if n := len(frame.lineTable); n > 0 {
+		last := &frame.lineTable[n-1]
+		if last.Line == info.Line && last.Column == info.Column {
+			return
+		}
+		if last.Pc == info.Pc {
+			*last = info
+			return
+		}
+	}
+	frame.lineTable = append(frame.lineTable, info)
+}
+
 func (ctx *Context) insCopyName(name string) {
 	ctx.writeIns(proto.INS_COPY_STACK_TOP)
 	ctx.frame.nt.Set(name, 0)
@@ -88,6 +202,11 @@ func (ctx *Context) insCall(wantRtnCnt byte, argCnt byte) {
 	ctx.writeByte(argCnt)
 }
 
+func (ctx *Context) insNewObject(argCnt byte) {
+	ctx.writeIns(proto.INS_NEW_OBJECT)
+	ctx.writeByte(argCnt)
+}
+
 func (ctx *Context) insTry(catchAddr uint32) int {
 	ctx.writeIns(proto.INS_TRY)
 	pos := len(ctx.frame.text)
@@ -95,6 +214,41 @@ func (ctx *Context) insTry(catchAddr uint32) int {
 	return pos
 }
 
+func (ctx *Context) insCallFinally(tb *tryBlock) {
+	ctx.writeIns(proto.INS_CALL_FINALLY)
+	tb.finallyCalls = append(tb.finallyCalls, len(ctx.frame.text))
+	ctx.writeUint(0)
+}
+
+// rethrow the exception on the top of stack
+func (ctx *Context) insRethrow() {
+	ctx.writeIns(proto.INS_LOAD_BUILTIN)
+	ctx.writeUint(builtinFuncs["throw"])
+	ctx.insCall(0, 1)
+}
+
+func (ctx *Context) enterTry(tb *tryBlock) {
+	ctx.frame.curTryLevel++
+	ctx.frame.tryBlocks = append(ctx.frame.tryBlocks, tb)
+}
+
+func (ctx *Context) leaveTry() {
+	ctx.frame.curTryLevel--
+	ctx.frame.tryBlocks = ctx.frame.tryBlocks[:ctx.frame.curTryLevel]
+}
+
+// jump out of try statements until try level is down to level, finally blocks of them will be called
+func (ctx *Context) jumpOutOfTry(level int) {
+	for i := ctx.frame.curTryLevel - 1; i >= level; i-- {
+		tb := ctx.frame.tryBlocks[i]
+		ctx.writeIns(proto.INS_END_TRY)
+		if tb.hasFinally {
+			ctx.insResizeNameTable(tb.nameCnt)
+			ctx.insCallFinally(tb)
+		}
+	}
+}
+
 func (ctx *Context) insReturn(argCnt uint32) {
 	ctx.writeIns(proto.INS_RETURN)
 	ctx.writeUint(argCnt)
@@ -211,18 +365,63 @@ func (ctx *Context) insLoadStdlib(idx uint32) {
 	ctx.writeUint(idx)
 }
 
+func (ctx *Context) insLoadHost(idx uint32) {
+	ctx.writeIns(proto.INS_LOAD_HOST)
+	ctx.writeUint(idx)
+}
+
+func (ctx *Context) insIterNext(idx uint32) {
+	ctx.writeIns(proto.INS_ITER_NEXT)
+	ctx.writeUint(idx)
+}
+
+func (ctx *Context) insIterUnpack(idx uint32, addr uint32) int {
+	ctx.writeIns(proto.INS_ITER_UNPACK)
+	ctx.writeUint(idx)
+	pos := len(ctx.frame.text)
+	ctx.writeUint(addr - uint32(pos+4))
+	return pos
+}
+
 func (ctx *Context) insStoreUpValue(idx uint32) {
 	ctx.writeIns(proto.INS_STORE_UPVALUE)
 	ctx.writeUint(idx)
 }
 
 func searchFrame(frame *StackFrame, name string) (uint32, bool) {
-	for nt := frame.nt; nt != nil; nt = nt.prev {
-		if v, ok := nt.nameTable[name]; ok {
-			return v.idx, true
+	return frame.nt.get(name)
+}
+
+// value of enum constant, false if name refers to a variable or function instead
+func (ctx *Context) enumValue(name string) (int64, bool) {
+	for frame := ctx.frame; frame != nil; frame = frame.prev {
+		if _, ok := searchFrame(frame, name); ok {
+			return 0, false
 		}
 	}
-	return 0, false
+	if _, ok := ctx.ft.funcMap[name]; ok {
+		return 0, false
+	}
+	idx, ok := ctx.ct.getEnum(name)
+	if !ok {
+		return 0, false
+	}
+	return ctx.ct.Constants[idx].(int64), true
+}
+
+// report an error if name to be assigned is a constant, name may be an upvalue
+func (ctx *Context) checkAssign(name string, line int) {
+	for frame := ctx.frame; frame != nil; frame = frame.prev {
+		if v, ok := frame.nt.lookup(name); ok {
+			if v.constant {
+				errorf(line, "cannot assign to constant '%s' declared at line %d", name, v.line)
+			}
+			return
+		}
+	}
+	if _, ok := ctx.enumValue(name); ok {
+		errorf(line, "cannot assign to enum constant '%s'", name)
+	}
 }
 
 func tryLoadUpValue(ctx *Context, name string) (upValueIdx uint32, ok bool) {
@@ -275,6 +474,20 @@ func (ctx *Context) insLoadName(name string, line int) {
 		return
 	}
 
+	// name is a class? constructor of the class is loaded
+	idx, ok = ctx.classes[name]
+	if ok {
+		ctx.insLoadAnonymous(idx)
+		return
+	}
+
+	// name is provided by host application? it may override a builtin function
+	idx, ok = ctx.hostGlobals[name]
+	if ok {
+		ctx.insLoadHost(idx)
+		return
+	}
+
 	// name is a builtin function?
 	idx, ok = builtinFuncs[name]
 	if ok {
@@ -283,8 +496,7 @@ func (ctx *Context) insLoadName(name string, line int) {
 		return
 	}
 
-	fmt.Printf("[%s:%d] undeclared name '%s'\n", curParsingFile, line, name)
-	os.Exit(0)
+	errorf(line, "undeclared name '%s'", name)
 }
 
 func (ctx *Context) insStoreName(name string, line int) {
@@ -301,8 +513,7 @@ func (ctx *Context) insStoreName(name string, line int) {
 		return
 	}
 
-	fmt.Printf("[%s:%d] undeclared name '%s'\n", curParsingFile, line, name)
-	os.Exit(0)
+	errorf(line, "undeclared name '%s'", name)
 }
 
 func (ctx *Context) enterBlock() {
@@ -335,52 +546,20 @@ type label struct {
 	name          string
 	addr          uint32
 	nameTableSize uint32
+	tryLevel      int         // try level of the block of label
+	block         interface{} // innermost switch or loop statement of label, see blockStack
 }
 
-var builtinFuncs = map[string]uint32{
-	"print":                0,
-	"len":                  1,
-	"append":               2,
-	"sub":                  3,
-	"type":                 4,
-	"delete":               5,
-	"clone":                6,
-	"__buffer_new":         7,
-	"__buffer_readNumber":  8,
-	"__buffer_writeNumber": 9,
-	"__buffer_toString":    10,
-	"__buffer_slice":       11,
-	"__buffer_concat":      12,
-	"__buffer_copy":        13,
-	"__buffer_from":        14,
-	"__open":               15,
-	"__read":               16,
-	"__write":              17,
-	"__close":              18,
-	"__seek":               19,
-	"__remove":             20,
-	"__fchmod":             21,
-	"__chmod":              22,
-	"__fchown":             23,
-	"__chown":              24,
-	"__fchdir":             25,
-	"__chdir":              26,
-	"__fstat":              27,
-	"__stat":               28,
-	"__rename":             29,
-	"__mkdir":              30,
-	"__exit":               31,
-	"__getenv":             32,
-	"__setenv":             33,
-	"__readdir":            34,
-	"__freaddir":           35,
-	"throw":                36,
-	"__args":               37,
-	"__getegid":            38,
-	"__geteuid":            39,
-	"__getgid":             40,
-	"__getpid":             41,
-	"__getppid":            42,
-	"__getuid":             43,
-	"__exec":               44,
+// indexes of builtin functions, see proto.BuiltinFuncs
+var builtinFuncs = func() map[string]uint32 {
+	funcs := make(map[string]uint32, len(proto.BuiltinFuncs))
+	for i, name := range proto.BuiltinFuncs {
+		funcs[name] = uint32(i)
+	}
+	return funcs
+}()
+
+// builtin classes are constructed by calling the builtin function of the same name
+var builtinClasses = map[string]bool{
+	"Error": true,
 }
@@ -2,10 +2,8 @@ package codegen
 
 import (
 	"encoding/binary"
-	"fmt"
 	"gscript/compiler/ast"
 	"gscript/proto"
-	"os"
 )
 
 func genExps(exps []ast.Exp, ctx *Context, wantCnt int) {
@@ -23,6 +21,7 @@ func genExps(exps []ast.Exp, ctx *Context, wantCnt int) {
 }
 
 func genExp(exp ast.Exp, ctx *Context, retCnt int) {
+	defer ctx.locate(exp)
 	switch exp := exp.(type) {
 	case *ast.NumberLiteralExp:
 		genNumberLiteralExp(exp, ctx)
@@ -36,6 +35,9 @@ func genExp(exp ast.Exp, ctx *Context, retCnt int) {
 	case *ast.StringLiteralExp:
 		genStringExp(exp, ctx)
 		retCnt--
+	case *ast.TemplateExp:
+		genTemplateExp(exp, ctx)
+		retCnt--
 	case *ast.NilExp:
 		genNilExp(exp, ctx)
 		retCnt--
@@ -67,8 +69,7 @@ func genExp(exp ast.Exp, ctx *Context, retCnt int) {
 		genFuncCallExp(exp, ctx, retCnt)
 		retCnt = 0
 	default:
-		fmt.Printf("[%s] unknown expression %v\n", curParsingFile, exp)
-		os.Exit(0)
+		errorf(ctx.parser.Positions[exp].Line, "unknown expression %v", exp)
 	}
 	for i := 0; i < retCnt; i++ {
 		ctx.insLoadNil()
@@ -76,15 +77,27 @@ func genExp(exp ast.Exp, ctx *Context, retCnt int) {
 }
 
 func genNewObjectExp(exp *ast.NewObjectExp, ctx *Context) {
-	ctx.writeIns(proto.INS_NEW_EMPTY_MAP)
+	if _, ok := ctx.classes[exp.Name]; !ok && builtinClasses[exp.Name] {
+		genExps(exp.Args, ctx, len(exp.Args))
+		ctx.writeIns(proto.INS_LOAD_BUILTIN)
+		ctx.writeUint(builtinFuncs[exp.Name])
+		ctx.setPos(exp)
+		ctx.insCall(1, byte(len(exp.Args)))
+		return
+	}
 	genExps(exp.Args, ctx, len(exp.Args))
-	idx, ok := ctx.classes[exp.Name]
-	if !ok {
-		fmt.Printf("[%s:%d] undefined class '%s'\n", curParsingFile, exp.Line, exp.Name)
-		os.Exit(0)
+	if idx, ok := ctx.classes[exp.Name]; ok {
+		ctx.insLoadAnonymous(idx)
+	} else if _, ok := searchFrame(ctx.frame, exp.Name); ok {
+		// class stored in a variable
+		ctx.insLoadName(exp.Name, exp.Line)
+	} else if _, ok := tryLoadUpValue(ctx, exp.Name); ok {
+		ctx.insLoadName(exp.Name, exp.Line)
+	} else {
+		errorf(exp.Line, "undefined class '%s'", exp.Name)
 	}
-	ctx.insLoadAnonymous(idx)
-	ctx.insCall(0, byte(len(exp.Args)))
+	ctx.setPos(exp)
+	ctx.insNewObject(byte(len(exp.Args)))
 }
 
 func genFuncLiteralExp(exp *ast.FuncLiteralExp, ctx *Context) {
@@ -102,7 +115,9 @@ func genFuncLiteralExp(exp *ast.FuncLiteralExp, ctx *Context) {
 
 func genFuncCallExp(exp *ast.FuncCallExp, ctx *Context, retCnt int) {
 	genExps(exp.Args, ctx, len(exp.Args))
+	checkSuperCall(exp.Func)
 	genExp(exp.Func, ctx, 1)
+	ctx.setPos(exp)
 	ctx.insCall(byte(retCnt), byte(len(exp.Args)))
 }
 
@@ -124,7 +139,71 @@ func genTernaryOpExp(exp *ast.TernaryOpExp, ctx *Context) {
 	binary.LittleEndian.PutUint32(ctx.frame.text[now-4:now], last-now)
 }
 
+// value of exp if it can be evaluated at compile time. Only integer arithmetic of number
+// literals and enum constants is folded, others are left to VM.
+func foldIntExp(exp ast.Exp, ctx *Context) (int64, bool) {
+	switch exp := exp.(type) {
+	case *ast.NumberLiteralExp:
+		v, ok := exp.Value.(int64)
+		return v, ok
+	case *ast.NameExp:
+		return ctx.enumValue(exp.Name)
+	case *ast.UnOpExp:
+		v, ok := foldIntExp(exp.Exp, ctx)
+		if !ok {
+			return 0, false
+		}
+		switch exp.Op {
+		case ast.UNOP_NEG:
+			return -v, true
+		case ast.UNOP_NOT:
+			return ^v, true
+		}
+	case *ast.BinOpExp:
+		a, ok := foldIntExp(exp.Exp1, ctx)
+		if !ok {
+			return 0, false
+		}
+		b, ok := foldIntExp(exp.Exp2, ctx)
+		if !ok {
+			return 0, false
+		}
+		if b == 0 && (exp.BinOp == ast.BINOP_IDIV || exp.BinOp == ast.BINOP_MOD) ||
+			b < 0 && (exp.BinOp == ast.BINOP_SHL || exp.BinOp == ast.BINOP_SHR) {
+			// runtime error is reported by VM
+			return 0, false
+		}
+		switch exp.BinOp {
+		case ast.BINOP_ADD:
+			return a + b, true
+		case ast.BINOP_SUB:
+			return a - b, true
+		case ast.BINOP_MUL:
+			return a * b, true
+		case ast.BINOP_IDIV:
+			return a / b, true
+		case ast.BINOP_MOD:
+			return a % b, true
+		case ast.BINOP_AND:
+			return a & b, true
+		case ast.BINOP_OR:
+			return a | b, true
+		case ast.BINOP_XOR:
+			return a ^ b, true
+		case ast.BINOP_SHL:
+			return a << b, true
+		case ast.BINOP_SHR:
+			return a >> b, true
+		}
+	}
+	return 0, false
+}
+
 func genBinOpExp(exp *ast.BinOpExp, ctx *Context) {
+	if v, ok := foldIntExp(exp, ctx); ok {
+		ctx.insLoadConst(v)
+		return
+	}
 	genExp(exp.Exp1, ctx, 1)
 	switch exp.BinOp {
 	case ast.BINOP_LAND:
@@ -135,34 +214,50 @@ func genBinOpExp(exp *ast.BinOpExp, ctx *Context) {
 		pos := ctx.insJumpIfLOr(0)
 		genExp(exp.Exp2, ctx, 1)
 		ctx.setSteps(pos, ctx.textSize())
+	case ast.BINOP_INSTANCEOF:
+		genExp(exp.Exp2, ctx, 1)
+		ctx.setPos(exp)
+		ctx.writeIns(proto.INS_INSTANCEOF)
 	default:
 		genExp(exp.Exp2, ctx, 1)
+		ctx.setPos(exp)
 		ctx.writeIns(byte(exp.BinOp-ast.BINOP_START) + proto.INS_BINARY_START)
 	}
 }
 
 func genUnOpExp(exp *ast.UnOpExp, ctx *Context) {
+	if v, ok := foldIntExp(exp, ctx); ok {
+		ctx.insLoadConst(v)
+		return
+	}
 	switch exp.Op {
 	case ast.UNOP_NOT:
 		genExp(exp.Exp, ctx, 1)
+		ctx.setPos(exp)
 		ctx.writeIns(proto.INS_UNARY_NOT)
 	case ast.UNOP_LNOT:
 		genExp(exp.Exp, ctx, 1)
+		ctx.setPos(exp)
 		ctx.writeIns(proto.INS_UNARY_LNOT)
 	case ast.UNOP_NEG:
 		genExp(exp.Exp, ctx, 1)
+		ctx.setPos(exp)
 		ctx.writeIns(proto.INS_UNARY_NEG)
 	case ast.UNOP_DEC: // --i
+		ctx.setPos(exp)
 		toAssignStmt(exp.Exp, ast.ASIGN_OP_SUBEQ, ctx)
 		genExp(exp.Exp, ctx, 1)
 	case ast.UNOP_INC: // ++i
+		ctx.setPos(exp)
 		toAssignStmt(exp.Exp, ast.ASIGN_OP_ADDEQ, ctx)
 		genExp(exp.Exp, ctx, 1)
 	case ast.UNOP_DEC_: // i--
 		genExp(exp.Exp, ctx, 1)
+		ctx.setPos(exp)
 		toAssignStmt(exp.Exp, ast.ASIGN_OP_SUBEQ, ctx)
 	case ast.UNOP_INC_: // i++
 		genExp(exp.Exp, ctx, 1)
+		ctx.setPos(exp)
 		toAssignStmt(exp.Exp, ast.ASIGN_OP_ADDEQ, ctx)
 	}
 }
@@ -179,6 +274,14 @@ func genArrLiteralExp(exp *ast.ArrLiteralExp, ctx *Context) {
 	ctx.writeUint(uint32(len(exp.Vals)))
 }
 
+func genTemplateExp(exp *ast.TemplateExp, ctx *Context) {
+	for _, part := range exp.Parts {
+		genExp(part, ctx, 1)
+	}
+	ctx.writeIns(proto.INS_CONCAT)
+	ctx.writeUint(uint32(len(exp.Parts)))
+}
+
 func genMapLiteralExp(exp *ast.MapLiteralExp, ctx *Context) {
 	for i, key := range exp.Keys {
 		ctx.insLoadConst(key)
@@ -211,6 +314,7 @@ func genStringExp(exp *ast.StringLiteralExp, ctx *Context) {
 func toAssignStmt(exp ast.Exp, op int, ctx *Context) {
 	stmt := &ast.VarAssignStmt{AssignOp: op, Rights: []ast.Exp{&ast.NumberLiteralExp{Value: int64(1)}}}
 	if e, ok := exp.(*ast.NameExp); ok {
+		stmt.Line = e.Line
 		stmt.Lefts = []ast.Var{{Prefix: e.Name}}
 		genVarAssignStmt(stmt, ctx)
 		return
@@ -15,14 +15,24 @@ type FuncTable struct {
 func newFuncTable(funcs []*ast.FuncDefStmt) *FuncTable {
 	ft := &FuncTable{
 		funcMap:   make(map[string]uint32),
-		funcTable: make([]proto.FuncProto, len(funcs)),
+		funcTable: make([]proto.FuncProto, 0, len(funcs)),
 	}
-	for i, f := range funcs {
-		ft.funcMap[f.Name] = uint32(i)
+	ft.addFuncs(funcs)
+	return ft
+}
+
+// a redefined function reuses the index of the old one
+func (ft *FuncTable) addFuncs(funcs []*ast.FuncDefStmt) {
+	for _, f := range funcs {
+		idx, ok := ft.funcMap[f.Name]
+		if !ok {
+			idx = uint32(len(ft.funcTable))
+			ft.funcMap[f.Name] = idx
+			ft.funcTable = append(ft.funcTable, proto.FuncProto{})
+		}
 		info := new(proto.BasicInfo)
 		info.Parameters = f.Parameters
 		info.VaArgs = f.VaArgs != ""
-		ft.funcTable[i].Info = info
+		ft.funcTable[idx] = proto.FuncProto{Info: info}
 	}
-	return ft
 }
@@ -1,10 +1,5 @@
 package codegen
 
-import (
-	"fmt"
-	"os"
-)
-
 type NameTable struct {
 	nameTable map[string]variable
 	nameIdx   *uint32
@@ -12,8 +7,9 @@ type NameTable struct {
 }
 
 type variable struct {
-	idx  uint32
-	line uint32
+	idx      uint32
+	line     uint32
+	constant bool // declared by const
 }
 
 func NewNameTable() *NameTable {
@@ -29,8 +25,7 @@ func newNameTable(nameIdx *uint32) *NameTable {
 
 func (nt *NameTable) Set(name string, line uint32) {
 	if v, ok := nt.nameTable[name]; ok {
-		fmt.Printf("[%s] variable '%s' already declared at line %d, but redecalres at line %d", curParsingFile, name, v.line, line)
-		os.Exit(0)
+		errorf(int(line), "variable '%s' already declared at line %d", name, v.line)
 	}
 	nt.nameTable[name] = variable{
 		idx:  *nt.nameIdx,
@@ -39,11 +34,23 @@ func (nt *NameTable) Set(name string, line uint32) {
 	*nt.nameIdx++
 }
 
+// mark variable declared in nt as a constant
+func (nt *NameTable) setConst(name string) {
+	v := nt.nameTable[name]
+	v.constant = true
+	nt.nameTable[name] = v
+}
+
 func (nt *NameTable) get(name string) (uint32, bool) {
+	v, ok := nt.lookup(name)
+	return v.idx, ok
+}
+
+func (nt *NameTable) lookup(name string) (variable, bool) {
 	for t := nt; t != nil; t = t.prev {
 		if v, ok := t.nameTable[name]; ok {
-			return v.idx, true
+			return v, true
 		}
 	}
-	return 0, false
+	return variable{}, false
 }
@@ -0,0 +1,183 @@
+package codegen
+
+import (
+	"gscript/compiler/ast"
+	"gscript/compiler/parser"
+	"gscript/proto"
+)
+
+// Session generates the main proto chunk by chunk, e.g. for REPL. Declarations of
+// previous chunks are visible to later ones. Codes of a chunk are appended to the main
+// proto and end with INS_STOP.
+type Session struct {
+	file string
+	ctx  *Context
+	top  *StackFrame // frame of top level codes
+	nt   *NameTable  // name table of top level variables
+	last *snapshot   // state before the last chunk
+}
+
+// state of Context to be restored if a chunk fails
+type snapshot struct {
+	textSize  int
+	lineTable []proto.LineInfo
+	names     map[string]variable
+	nameIdx   uint32
+	labels    map[string]label
+	constCnt  int
+	consMap   map[interface{}]uint32
+	enums     map[string]enum
+	funcs     []proto.FuncProto
+	funcMap   map[string]uint32
+	anonymous []proto.AnonymousFuncProto
+	classes   map[string]uint32
+}
+
+func NewSession(file string, hostGlobals map[string]uint32) *Session {
+	ctx := newContext(&parser.Parser{})
+	ctx.hostGlobals = hostGlobals
+	ctx.writeIns(proto.INS_STOP)
+	return &Session{file: file, ctx: ctx, top: ctx.frame, nt: ctx.frame.nt}
+}
+
+// GenChunk generates codes of prog and returns the main proto and address where codes of
+// prog start. It panics ast.ErrorList if prog is invalid, and the session is kept unchanged.
+func (s *Session) GenChunk(parser *parser.Parser, prog *ast.Program, imports []Import) (proto.Proto, uint32) {
+	return s.gen(parser, func(ctx *Context) {
+		ctx.ft.addFuncs(parser.FuncDefs)
+		addClasses(ctx.classes, parser.ClassStmts, ctx.ft)
+		genEnumStmt(parser.EnumStmts, ctx)
+		genClassStmts(parser.ClassStmts, ctx)
+		genImports(imports, ctx)
+		genInherits(parser.ClassStmts, ctx)
+		genBlockStmts(prog.BlockStmts, ctx)
+	})
+}
+
+// GenExp is like GenChunk, but value of exp is left in evaluation stack when codes finish
+func (s *Session) GenExp(parser *parser.Parser, exp ast.Exp) (proto.Proto, uint32) {
+	return s.gen(parser, func(ctx *Context) {
+		genExp(exp, ctx, 1)
+	})
+}
+
+func (s *Session) gen(parser *parser.Parser, gen func(ctx *Context)) (proto.Proto, uint32) {
+	ctx := s.ctx
+	s.last = s.save()
+	defer func() {
+		r := recover()
+		if r != nil || len(ctx.errs) > 0 {
+			s.Rollback()
+		}
+		ctx.checkErrors(r, s.file)
+	}()
+	ctx.parser = parser
+	// overwrite INS_STOP of the last chunk
+	ctx.frame.text = ctx.frame.text[:len(ctx.frame.text)-1]
+	start := ctx.textSize()
+	gen(ctx)
+	ctx.writeIns(proto.INS_STOP)
+	return s.proto(), start
+}
+
+func (s *Session) proto() proto.Proto {
+	return proto.Proto{
+		FilePath:       s.file,
+		Text:           s.top.text,
+		LineTable:      s.top.lineTable,
+		Consts:         s.ctx.ct.Constants,
+		Funcs:          s.ctx.ft.funcTable,
+		AnonymousFuncs: s.ctx.ft.anonymousFuncs,
+	}
+}
+
+func (s *Session) save() *snapshot {
+	ctx := s.ctx
+	ss := &snapshot{
+		textSize:  len(s.top.text),
+		lineTable: append([]proto.LineInfo(nil), s.top.lineTable...),
+		names:     make(map[string]variable, len(s.nt.nameTable)),
+		nameIdx:   *s.nt.nameIdx,
+		labels:    make(map[string]label, len(s.top.validLabels)),
+		constCnt:  len(ctx.ct.Constants),
+		consMap:   make(map[interface{}]uint32, len(ctx.ct.ConsMap)),
+		enums:     make(map[string]enum, len(ctx.ct.enums)),
+		funcs:     append([]proto.FuncProto(nil), ctx.ft.funcTable...),
+		funcMap:   make(map[string]uint32, len(ctx.ft.funcMap)),
+		anonymous: append([]proto.AnonymousFuncProto(nil), ctx.ft.anonymousFuncs...),
+		classes:   make(map[string]uint32, len(ctx.classes)),
+	}
+	for k, v := range s.nt.nameTable {
+		ss.names[k] = v
+	}
+	for k, v := range s.top.validLabels {
+		ss.labels[k] = v
+	}
+	for k, v := range ctx.ct.ConsMap {
+		ss.consMap[k] = v
+	}
+	for k, v := range ctx.ct.enums {
+		ss.enums[k] = v
+	}
+	for k, v := range ctx.ft.funcMap {
+		ss.funcMap[k] = v
+	}
+	for k, v := range ctx.classes {
+		ss.classes[k] = v
+	}
+	return ss
+}
+
+// Rollback discards the last chunk, e.g. if it fails when running. It returns the main
+// proto without the chunk.
+func (s *Session) Rollback() proto.Proto {
+	ss, ctx := s.last, s.ctx
+	if ss == nil {
+		return s.proto()
+	}
+	s.last = nil
+	top := s.top
+	// codes of the chunk may overwrite INS_STOP
+	top.text = append(top.text[:ss.textSize-1], proto.INS_STOP)
+	top.lineTable = ss.lineTable
+	s.nt.nameTable, *s.nt.nameIdx = ss.names, ss.nameIdx
+	top.validLabels = ss.labels
+	// generating may stop in a nested block or function
+	top.nt, top.bs, top.tryBlocks, top.curTryLevel = s.nt, newBlockStack(), nil, 0
+	top.labelScopes, top.gotos = nil, nil
+	ctx.frame = top
+	ctx.ct.Constants, ctx.ct.ConsMap, ctx.ct.enums = ctx.ct.Constants[:ss.constCnt], ss.consMap, ss.enums
+	ctx.ft.funcTable, ctx.ft.funcMap, ctx.ft.anonymousFuncs = ss.funcs, ss.funcMap, ss.anonymous
+	ctx.classes = ss.classes
+	return s.proto()
+}
+
+// Names returns names visible at top level, including functions, classes and enum constants
+func (s *Session) Names() []string {
+	var names []string
+	for name := range s.nt.nameTable {
+		names = append(names, name)
+	}
+	for name := range s.ctx.ft.funcMap {
+		names = append(names, name)
+	}
+	for name := range s.ctx.classes {
+		names = append(names, name)
+	}
+	for name := range s.ctx.ct.enums {
+		names = append(names, name)
+	}
+	for name := range s.ctx.hostGlobals {
+		names = append(names, name)
+	}
+	for name := range builtinFuncs {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Variable returns index of the top level variable name in symbol table
+func (s *Session) Variable(name string) (idx uint32, ok bool) {
+	v, ok := s.nt.nameTable[name]
+	return v.idx, ok
+}
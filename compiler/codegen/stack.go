@@ -16,6 +16,12 @@ type switchBlock struct {
 	_fallthrough *int
 }
 
+type tryBlock struct {
+	nameCnt      uint32 // size of name table when entering try statement
+	hasFinally   bool
+	finallyCalls []int // positions of CALL_FINALLY to be set to address of finally block
+}
+
 type blockStack struct {
 	cur interface{}
 }
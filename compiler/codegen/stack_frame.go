@@ -1,16 +1,22 @@
 package codegen
 
+import "gscript/proto"
+
 type StackFrame struct {
 	prev        *StackFrame
 	nt          *NameTable
 	vt          *UpValueTable
 	bs          *blockStack
 	validLabels map[string]label
+	labelScopes []map[string]label // labels of blocks being generated, the innermost is the last
+	gotos       []unhandledGoto    // goto statements whose labels are not generated yet
 	returnAtEnd bool
 	text        []byte
+	lineTable   []proto.LineInfo
 
 	nowParsingAnonymous int
 	curTryLevel         int
+	tryBlocks           []*tryBlock // try statements being generated, len(tryBlocks) == curTryLevel
 }
 
 func newStackFrame() *StackFrame {
@@ -5,11 +5,9 @@ import (
 	"gscript/compiler/ast"
 	"gscript/compiler/parser"
 	"gscript/proto"
-	"os"
 )
 
 var stdLibGenMode bool
-var curParsingFile string
 
 func SetStdLibGenMode() {
 	stdLibGenMode = true
@@ -17,24 +15,30 @@ func SetStdLibGenMode() {
 
 type Import struct {
 	Line        uint32
-	ProtoNumber uint32
+	ProtoNumber uint32 // index of host value if Host is true
 	Alias       string
 	StdLib      bool
+	Host        bool // module provided by host application
 }
 
-func Gen(parser *parser.Parser, prog *ast.Program, imports []Import, protoNum uint32) proto.Proto {
-	curParsingFile = prog.File
-
+// Gen generates proto of prog, panics ast.ErrorList if prog is invalid. hostGlobals maps
+// names of globals provided by host application to indexes of host values.
+func Gen(parser *parser.Parser, prog *ast.Program, imports []Import, protoNum uint32, hostGlobals map[string]uint32) proto.Proto {
 	// number of main proto is zero
 	mainProto := protoNum == 0
 	ctx := newContext(parser)
+	defer func() {
+		ctx.checkErrors(recover(), prog.File)
+	}()
 	ctx.protoNum = protoNum
+	ctx.hostGlobals = hostGlobals
 
 	// make all enum and class statements global
 	genEnumStmt(parser.EnumStmts, ctx)
 	genClassStmts(parser.ClassStmts, ctx)
 
 	genImports(imports, ctx)
+	genInherits(parser.ClassStmts, ctx)
 	genBlockStmts(prog.BlockStmts, ctx)
 	if !mainProto || stdLibGenMode {
 		genExport(prog.Export, ctx)
@@ -44,12 +48,18 @@ func Gen(parser *parser.Parser, prog *ast.Program, imports []Import, protoNum ui
 
 	return proto.Proto{
 		Text:           ctx.frame.text,
+		LineTable:      ctx.frame.lineTable,
 		Consts:         ctx.ct.Constants,
 		Funcs:          ctx.ft.funcTable,
 		AnonymousFuncs: ctx.ft.anonymousFuncs,
 	}
 }
 
+// report a compile error at line, source file is filled in by Gen
+func errorf(line int, format string, args ...interface{}) {
+	panic(&ast.Error{Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
 func genExport(export ast.Export, ctx *Context) {
 	exp := export.Exp
 	if export.Exp == nil {
@@ -61,7 +71,10 @@ func genExport(export ast.Export, ctx *Context) {
 
 func genImports(imports []Import, ctx *Context) {
 	for _, _import := range imports {
-		if _import.StdLib {
+		ctx.setLine(_import.Line, 0)
+		if _import.Host {
+			ctx.insLoadHost(_import.ProtoNumber)
+		} else if _import.StdLib {
 			ctx.insLoadStdlib(_import.ProtoNumber)
 		} else {
 			ctx.insLoadProto(_import.ProtoNumber)
@@ -71,19 +84,37 @@ func genImports(imports []Import, ctx *Context) {
 }
 
 func genClassStmts(stmts []*ast.ClassStmt, ctx *Context) {
-	for i, stmt := range stmts {
-		genClassStmt(stmt, ctx)
-		ctx.ft.anonymousFuncs[i].Info.Text = ctx.frame.text
+	frame := ctx.frame
+	for _, stmt := range stmts {
 		ctx.frame = newStackFrame()
+		genClassStmt(stmt, ctx)
+		idx := ctx.classes[stmt.Name]
+		ctx.ft.anonymousFuncs[idx].Info.Text = ctx.frame.text
+		ctx.ft.anonymousFuncs[idx].Info.LineTable = ctx.frame.lineTable
 	}
+	ctx.frame = frame
 }
 
 func genClassStmt(stmt *ast.ClassStmt, ctx *Context) {
 	__self := stmt.Constructor
 	if __self != nil {
 		collectArgs(&__self.FuncLiteral, ctx)
+	} else if stmt.Parent != nil {
+		// arguments are passed to constructor of parent
+		ctx.insPushName("#args", 0)
+	}
+	var blocks []ast.BlockStmt
+	if __self != nil {
+		blocks = __self.Block.Blocks
+	}
+	if stmt.Parent != nil {
+		ctx.insLoadNil()
+		ctx.insPushName("super", 0)
 	}
 	ctx.insCopyName("this")
+	if stmt.Parent != nil {
+		blocks = genSuperCall(stmt, blocks, ctx)
+	}
 	for i := range stmt.AttrName {
 		ctx.insLoadConst(stmt.AttrName[i])
 		genExp(stmt.AttrValue[i], ctx, 1)
@@ -91,85 +122,224 @@ func genClassStmt(stmt *ast.ClassStmt, ctx *Context) {
 	}
 
 	// codes of __self
-	if __self != nil {
-		genBlockStmts(__self.Block.Blocks, ctx)
-	}
+	genBlockStmts(blocks, ctx)
 	if !ctx.frame.returnAtEnd {
 		genReturnStmt(&ast.ReturnStmt{}, ctx)
 	}
 }
 
+// call constructor of parent with the same object before attributes of class are set.
+// The call is 'super(...)' if it is the first statement of __self, otherwise arguments
+// of the class are passed if __self is not defined. Then attributes set by parent are
+// copied to 'super', so that methods can call overridden methods by 'super.method()'.
+// The rest statements of __self are returned.
+func genSuperCall(stmt *ast.ClassStmt, blocks []ast.BlockStmt, ctx *Context) []ast.BlockStmt {
+	var args []ast.Exp
+	if len(blocks) > 0 {
+		if call, ok := blocks[0].(*ast.NamedFuncCallStmt); ok && call.Prefix == "super" &&
+			len(call.CallTails) == 1 && len(call.CallTails[0].Attrs) == 0 {
+			ctx.setPos(call)
+			args, blocks = call.CallTails[0].Args, blocks[1:]
+		}
+	}
+	if stmt.Constructor == nil {
+		ctx.insLoadName("#args", 0)
+	} else {
+		genExps(args, ctx, len(args))
+		ctx.writeIns(proto.INS_SLICE_NEW)
+		ctx.writeUint(uint32(len(args)))
+	}
+	ctx.insLoadAnonymous(ctx.classes[stmt.Name])
+	ctx.writeIns(proto.INS_CALL_SUPER)
+
+	ctx.insLoadName("this", 0)
+	ctx.writeIns(proto.INS_LOAD_BUILTIN)
+	ctx.writeUint(builtinFuncs["clone"])
+	ctx.insCall(1, 1)
+	ctx.insStoreName("super", 0)
+	return blocks
+}
+
+// set parents of classes, parents are evaluated after modules are imported
+func genInherits(stmts []*ast.ClassStmt, ctx *Context) {
+	for _, stmt := range stmts {
+		if stmt.Parent == nil {
+			continue
+		}
+		ctx.try(stmt, func() {
+			ctx.insLoadAnonymous(ctx.classes[stmt.Name])
+			genExp(stmt.Parent, ctx, 1)
+			ctx.setPos(stmt)
+			ctx.writeIns(proto.INS_INHERIT)
+		})
+	}
+}
+
 func genBlockStmts(stmts []ast.BlockStmt, ctx *Context) (varDecl bool) {
-	var gotos []unhandledGoto
+	frame := ctx.frame
+	gotoCnt := len(frame.gotos)
+	// labels can be referred by goto statements before them
+	scope := map[string]label{}
+	for _, stmt := range stmts {
+		if stmt, ok := stmt.(*ast.LabelStmt); ok {
+			scope[stmt.Name] = label{name: stmt.Name, tryLevel: frame.curTryLevel, block: frame.bs.top()}
+		}
+	}
+	frame.labelScopes = append(frame.labelScopes, scope)
+	defer func() { frame.labelScopes = frame.labelScopes[:len(frame.labelScopes)-1] }()
 	for _, stmt := range stmts {
 		ctx.frame.returnAtEnd = false
 		if block, ok := stmt.(ast.Block); ok {
-			genBlock(block, ctx)
+			ctx.try(stmt, func() { genBlock(block, ctx) })
 			continue
 		}
+		ctx.setPos(stmt)
 		switch stmt := stmt.(type) {
 		case *ast.VarDeclStmt:
-			genVarDeclStmt(stmt, ctx)
+			ctx.try(stmt, func() { genVarDeclStmt(stmt, ctx) })
 			varDecl = true
-		case *ast.VarAssignStmt:
-			genVarAssignStmt(stmt, ctx)
-		case *ast.IfStmt:
-			genIfStmt(stmt, ctx)
-		case *ast.WhileStmt:
-			genWhileStmt(stmt, ctx)
-		case *ast.ForStmt:
-			genForStmt(stmt, ctx)
-		case *ast.BreakStmt:
-			genBreakStmt(stmt, ctx)
-		case *ast.ContinueStmt:
-			genContinueStmt(stmt, ctx)
-		case *ast.SwitchStmt:
-			genSwitchStmt(stmt, ctx)
-		case *ast.FallthroughStmt:
-			genFallthroughStmt(stmt, ctx)
-		case *ast.NamedFuncCallStmt:
-			genFuncCallStmt(stmt, ctx)
-		case *ast.ReturnStmt:
-			genReturnStmt(stmt, ctx)
-		case *ast.AnonymousFuncCallStmt:
-			genAnonymousFuncCallStmt(stmt, ctx)
-		case *ast.FuncDefStmt:
-			genFuncDefStmt(stmt, ctx)
-		case *ast.TryCatchStmt:
-			genTryCatchStmt(stmt, ctx)
-		case *ast.LoopStmt:
-			fmt.Printf("[%s] do not support loop statement for now\n", curParsingFile)
-			os.Exit(0)
 		case *ast.LabelStmt:
-			ctx.frame.validLabels[stmt.Name] = label{
-				name:          stmt.Name,
-				addr:          ctx.textSize(),
-				nameTableSize: *ctx.frame.nt.nameIdx,
-			}
+			l := scope[stmt.Name]
+			l.addr, l.nameTableSize = ctx.textSize(), *ctx.frame.nt.nameIdx
+			ctx.frame.validLabels[stmt.Name] = l
 			// when exit block, make labels inside block invalid
 			defer func() { delete(ctx.frame.validLabels, stmt.Name) }()
 		case *ast.GotoStmt:
-			gotos = append(gotos, genGotoStmt(stmt, ctx))
+			ctx.try(stmt, func() { genGotoStmt(stmt, ctx) })
 		case *ast.EnumStmt, *ast.ClassStmt:
 			continue
 		default:
-			fmt.Printf("[%s] invalid statment %v\n", curParsingFile, stmt)
-			os.Exit(0)
+			ctx.try(stmt, func() { genStmt(stmt, ctx) })
+			// return inside if, loop or try statements may be skipped
+			if _, ok := stmt.(*ast.ReturnStmt); !ok {
+				ctx.frame.returnAtEnd = false
+			}
 		}
 	}
-	handleGoto(ctx, gotos)
+	handleGoto(ctx, gotoCnt, scope)
 	return
 }
 
+func genStmt(stmt ast.BlockStmt, ctx *Context) {
+	switch stmt := stmt.(type) {
+	case *ast.VarAssignStmt:
+		genVarAssignStmt(stmt, ctx)
+	case *ast.IfStmt:
+		genIfStmt(stmt, ctx)
+	case *ast.WhileStmt:
+		genWhileStmt(stmt, ctx)
+	case *ast.ForStmt:
+		genForStmt(stmt, ctx)
+	case *ast.BreakStmt:
+		genBreakStmt(stmt, ctx)
+	case *ast.ContinueStmt:
+		genContinueStmt(stmt, ctx)
+	case *ast.SwitchStmt:
+		genSwitchStmt(stmt, ctx)
+	case *ast.FallthroughStmt:
+		genFallthroughStmt(stmt, ctx)
+	case *ast.NamedFuncCallStmt:
+		genFuncCallStmt(stmt, ctx)
+	case *ast.ReturnStmt:
+		genReturnStmt(stmt, ctx)
+	case *ast.AnonymousFuncCallStmt:
+		genAnonymousFuncCallStmt(stmt, ctx)
+	case *ast.FuncDefStmt:
+		genFuncDefStmt(stmt, ctx)
+	case *ast.TryCatchStmt:
+		genTryCatchStmt(stmt, ctx)
+	case *ast.LoopStmt:
+		genLoopStmt(stmt, ctx)
+	default:
+		errorf(ctx.parser.Positions[stmt].Line, "invalid statment %v", stmt)
+	}
+}
+
+/*
+------------------------------
+try {
+	try_block
+} catch(e) {
+	catch_block
+} finally {
+	finally_block
+}
+other_code
+------------------------------
+the code above will be translated to following code:
+
+	try p0
+	try_block
+	end_try
+	call_finally p2
+	jump end
+p0:
+	try p1
+	push_name e
+	catch_block
+	end_try
+	call_finally p2
+	jump end
+p1:
+	call_finally p2
+	rethrow
+p2:
+	finally_block
+	end_finally
+end:
+	other_code
+
+if there is no catch, p0 is the same as p1; if there is no finally, catch block won't be protected
+by "try p1", and code of "call_finally", p1 and p2 will be omitted.
+break, continue and return inside try or catch block also call finally block before jumping out.
+*/
 func genTryCatchStmt(stmt *ast.TryCatchStmt, ctx *Context) {
-	ctx.frame.curTryLevel++
+	tb := &tryBlock{
+		nameCnt: *ctx.frame.nt.nameIdx,
+		// try without catch must rethrow the exception after finally block, even if finally block is empty
+		hasFinally: stmt.FinallyBlocks != nil || !stmt.HasCatch,
+	}
+	var ends []int
+
+	ctx.enterTry(tb)
 	catch := ctx.insTry(0)
 	genStmtsWithBlock(stmt.TryBlocks, ctx)
 	ctx.writeIns(proto.INS_END_TRY)
-	finally := ctx.insJumpRel(0)
-	ctx.frame.curTryLevel--
+	ctx.leaveTry()
+	if tb.hasFinally {
+		ctx.insCallFinally(tb)
+	}
+	ends = append(ends, ctx.insJumpRel(0))
 
 	ctx.setSteps(catch, ctx.textSize())
+	if stmt.HasCatch {
+		genCatchBlock(stmt, tb, ctx, &ends)
+	}
+	if !tb.hasFinally {
+		for _, pos := range ends {
+			ctx.setSteps(pos, ctx.textSize())
+		}
+		return
+	}
+	ctx.insCallFinally(tb)
+	ctx.insRethrow()
+
+	for _, pos := range tb.finallyCalls {
+		ctx.setSteps(pos, ctx.textSize())
+	}
+	genStmtsWithBlock(stmt.FinallyBlocks, ctx)
+	ctx.writeIns(proto.INS_END_FINALLY)
+	for _, pos := range ends {
+		ctx.setSteps(pos, ctx.textSize())
+	}
+}
+
+func genCatchBlock(stmt *ast.TryCatchStmt, tb *tryBlock, ctx *Context, ends *[]int) {
+	var rethrow int
+	if tb.hasFinally {
+		ctx.enterTry(tb)
+		rethrow = ctx.insTry(0)
+	}
 	ctx.enterBlock()
 	size := *ctx.frame.nt.nameIdx
 	if stmt.CatchValue != "" {
@@ -180,7 +350,13 @@ func genTryCatchStmt(stmt *ast.TryCatchStmt, ctx *Context) {
 		ctx.insPopTop()
 		ctx.leaveBlock(size, genBlockStmts(stmt.CatchBlocks, ctx))
 	}
-	ctx.setSteps(finally, ctx.textSize())
+	if tb.hasFinally {
+		ctx.writeIns(proto.INS_END_TRY)
+		ctx.leaveTry()
+		ctx.insCallFinally(tb)
+		*ends = append(*ends, ctx.insJumpRel(0))
+		ctx.setSteps(rethrow, ctx.textSize())
+	}
 }
 
 func genFuncDefStmt(stmt *ast.FuncDefStmt, ctx *Context) {
@@ -216,6 +392,7 @@ func bindUpValue(ctx *Context, funcIdx uint32, anonymous bool) {
 
 	if anonymous {
 		ctx.ft.anonymousFuncs[ctx.frame.nowParsingAnonymous].Info.Text = oldFrame.text
+		ctx.ft.anonymousFuncs[ctx.frame.nowParsingAnonymous].Info.LineTable = oldFrame.lineTable
 		ft := ctx.ft.anonymousFuncs
 		for _, upValue := range upValues {
 			vptr := getUpValueIdx(ctx.frame, ctx, &upValue)
@@ -223,6 +400,7 @@ func bindUpValue(ctx *Context, funcIdx uint32, anonymous bool) {
 		}
 	} else {
 		ctx.ft.funcTable[funcIdx].Info.Text = oldFrame.text
+		ctx.ft.funcTable[funcIdx].Info.LineTable = oldFrame.lineTable
 		ft := ctx.ft.funcTable
 		for _, upValue := range upValues {
 			ft[funcIdx].UpValues = append(ft[funcIdx].UpValues, upValue.nameIdx)
@@ -244,7 +422,7 @@ func getUpValueIdx(frame *StackFrame, ctx *Context, upValue *UpValue) proto.UpVa
 }
 
 func genAnonymousFuncCallStmt(stmt *ast.AnonymousFuncCallStmt, ctx *Context) {
-	genFuncCall(&ast.FuncLiteralExp{FuncLiteral: stmt.FuncLiteral}, stmt.CallTails, ctx)
+	genFuncCall(&ast.FuncLiteralExp{FuncLiteral: stmt.FuncLiteral}, stmt.CallTails, stmt, ctx)
 }
 
 func genReturnStmt(stmt *ast.ReturnStmt, ctx *Context) {
@@ -252,14 +430,16 @@ func genReturnStmt(stmt *ast.ReturnStmt, ctx *Context) {
 	for _, exp := range stmt.Args {
 		genExp(exp, ctx, 1)
 	}
+	ctx.jumpOutOfTry(0)
 	ctx.insReturn(uint32(len(stmt.Args)))
 }
 
 func genFuncCallStmt(stmt *ast.NamedFuncCallStmt, ctx *Context) {
-	genFuncCall(&ast.NameExp{Name: stmt.Prefix}, stmt.CallTails, ctx)
+	line := ctx.parser.Positions[stmt].Line
+	genFuncCall(&ast.NameExp{Line: line, Name: stmt.Prefix}, stmt.CallTails, stmt, ctx)
 }
 
-func genFuncCall(exp ast.Exp, callTails []ast.CallTail, ctx *Context) {
+func genFuncCall(exp ast.Exp, callTails []ast.CallTail, stmt ast.Stmt, ctx *Context) {
 	last := len(callTails) - 1
 	for i, callTail := range callTails {
 		var wantRetCnt byte
@@ -270,50 +450,90 @@ func genFuncCall(exp ast.Exp, callTails []ast.CallTail, ctx *Context) {
 		genExps(callTail.Args, ctx, len(callTail.Args))
 		// function
 		if i == 0 {
+			if len(callTail.Attrs) == 0 {
+				checkSuperCall(exp)
+			}
 			genExp(exp, ctx, 1)
 		}
 		for _, attr := range callTail.Attrs {
 			genExp(attr, ctx, 1)
+			ctx.setPos(stmt)
 			ctx.writeIns(proto.INS_BINARY_ATTR)
 		}
 
 		// call function
+		ctx.setPos(stmt)
 		ctx.insCall(wantRetCnt, byte(len(callTail.Args)))
 	}
 }
 
-func handleGoto(ctx *Context, gotos []unhandledGoto) {
-	for _, _goto := range gotos {
-		label, ok := ctx.frame.validLabels[_goto.label]
+// super(...) is compiled by genSuperCall only if it is the first statement of __self,
+// elsewhere super is the copy of parent's attributes, which is not callable
+func checkSuperCall(fn ast.Exp) {
+	if name, ok := fn.(*ast.NameExp); ok && name.Name == "super" {
+		errorf(name.Line, "super(...) can only be the first statement of __self")
+	}
+}
+
+// set addresses of goto statements generated since the from-th, whose labels are in scope. Others
+// are left to outer blocks, unless the block is the outermost one.
+func handleGoto(ctx *Context, from int, scope map[string]label) {
+	frame := ctx.frame
+	outermost := len(frame.labelScopes) == 1
+	pending := frame.gotos[:from]
+	for _, _goto := range frame.gotos[from:] {
+		if _, ok := scope[_goto.label]; !ok && !outermost {
+			pending = append(pending, _goto)
+			continue
+		}
+		label, ok := frame.validLabels[_goto.label]
 		if !ok {
-			fmt.Printf("[%s:%d] invalid goto label: '%s'", curParsingFile, _goto.line, _goto.label)
-			os.Exit(0)
+			errorf(_goto.line, "invalid goto label: '%s'", _goto.label)
 		}
 		ctx.setSteps(_goto.jumpPos, label.addr)
-		ctx.setSteps(_goto.resizePos, label.nameTableSize)
+		ctx.setAddr(_goto.resizePos, label.nameTableSize)
 	}
+	frame.gotos = pending
 }
 
-func genGotoStmt(stmt *ast.GotoStmt, ctx *Context) unhandledGoto {
-	if ctx.frame.curTryLevel != 0 {
-		fmt.Printf("[%s:%d] goto statement in try block is not allowed!\n", curParsingFile, stmt.Line)
-		os.Exit(0)
+// goto can jump to labels of the current block and outer blocks, finally blocks of try
+// statements it jumps out of are called
+func genGotoStmt(stmt *ast.GotoStmt, ctx *Context) {
+	frame := ctx.frame
+	target, ok := label{}, false
+	for i := len(frame.labelScopes) - 1; i >= 0 && !ok; i-- {
+		target, ok = frame.labelScopes[i][stmt.Label]
+	}
+	if !ok {
+		// labels of previous inputs of REPL
+		if target, ok = frame.validLabels[stmt.Label]; !ok {
+			errorf(stmt.Line, "invalid goto label: '%s'", stmt.Label)
+		}
+	}
+	// pop values of switch statements between goto and label
+	for cur := frame.bs.top(); cur != target.block; {
+		if sb, ok := cur.(*switchBlock); ok {
+			ctx.insPopTop()
+			cur = sb.prev
+		} else {
+			cur = cur.(*forBlock).prev
+		}
 	}
+	ctx.jumpOutOfTry(target.tryLevel)
 	resizePos := ctx.insResizeNameTable(0)
 	jumpPos := ctx.insJumpRel(0)
-	return unhandledGoto{
+	frame.gotos = append(frame.gotos, unhandledGoto{
 		line:      stmt.Line,
 		label:     stmt.Label,
 		resizePos: resizePos,
 		jumpPos:   jumpPos,
-	}
+	})
 }
 
 func genFallthroughStmt(stmt *ast.FallthroughStmt, ctx *Context) {
 	b := ctx.frame.bs.latestSwitch()
 	if b == nil {
-		fmt.Printf("[%s] found no matched switch statement for fallthrough at line %d\n", curParsingFile, stmt.Line)
-		os.Exit(0)
+		errorf(stmt.Line, "found no matched switch statement for fallthrough")
 	}
 	ctx.insResizeNameTable(b.nameCnt)
 	pos := ctx.insJumpRel(0)
@@ -323,36 +543,31 @@ func genFallthroughStmt(stmt *ast.FallthroughStmt, ctx *Context) {
 func genContinueStmt(stmt *ast.ContinueStmt, ctx *Context) {
 	b := ctx.frame.bs.latestFor()
 	if b == nil {
-		fmt.Printf("[%s] found no matched loop statement for continue at line %d\n", curParsingFile, stmt.Line)
-		os.Exit(0)
+		errorf(stmt.Line, "found no matched loop statement for continue")
 	}
-	outerTryLevel := b.curTryLevel
-	for top := ctx.frame.curTryLevel; outerTryLevel < top; outerTryLevel++ {
-		ctx.writeIns(proto.INS_END_TRY)
+	// pop values of switch statements inside the loop
+	for cur := ctx.frame.bs.cur; cur != b; cur = cur.(*switchBlock).prev {
+		ctx.insPopTop()
 	}
+	ctx.jumpOutOfTry(b.curTryLevel)
 	b.continues = append(b.continues, ctx.insJumpRel(0))
 }
 
 func genBreakStmt(stmt *ast.BreakStmt, ctx *Context) {
 	b := ctx.frame.bs.top()
 	if b == nil {
-		fmt.Printf("[%s] found no matched switch or loop statement for break at line %d\n", curParsingFile, stmt.Line)
-		os.Exit(0)
+		errorf(stmt.Line, "found no matched switch or loop statement for break")
 	}
 	var breaks *[]int
-	var outerTryLevel int
 	if fb, ok := b.(*forBlock); ok {
-		outerTryLevel = fb.curTryLevel
+		ctx.jumpOutOfTry(fb.curTryLevel)
 		breaks = &fb.breaks
 	} else {
-		outerTryLevel = fb.curTryLevel
 		sb := b.(*switchBlock)
+		ctx.jumpOutOfTry(sb.curTryLevel)
 		breaks = &sb.breaks
 		ctx.insResizeNameTable(sb.nameCnt)
 	}
-	for top := ctx.frame.curTryLevel; outerTryLevel < top; outerTryLevel++ {
-		ctx.writeIns(proto.INS_END_TRY)
-	}
 	*breaks = append(*breaks, ctx.insJumpRel(0))
 }
 
@@ -449,8 +664,7 @@ func genSwitchStmt(stmt *ast.SwitchStmt, ctx *Context) {
 	}
 	// unhandled fallthrough at last switch case
 	if sb._fallthrough != nil {
-		fmt.Printf("[%s] fallthrough should not appear at last case of switch statement\n", curParsingFile)
-		os.Exit(0)
+		errorf(ctx.parser.Positions[stmt].Line, "fallthrough should not appear at last case of switch statement")
 	}
 	end := ctx.textSize()
 	for _, end_ptr := range end_ptrs {
@@ -534,6 +748,79 @@ func genForStmt(stmt *ast.ForStmt, ctx *Context) {
 	ctx.frame.bs.pop()
 }
 
+/*
+------------------------------
+loop(let k,v : e0){
+	block_code
+}
+other_code
+------------------------------
+the code above will be translated to following code:
+
+	genExp(e0)
+	iter_new
+	push_name #iterator
+p0:
+	iter_next #iterator
+	iter_unpack #iterator p2
+	push_name v
+	push_name k		# pop_top if k is omitted
+	block_code
+p3:
+	resize_nametable
+	jump p0
+p2:
+	resize_nametable
+	other_code
+*/
+func genLoopStmt(stmt *ast.LoopStmt, ctx *Context) {
+	line := ctx.parser.Positions[stmt].Line
+	ctx.enterBlock()
+	startSize := *ctx.frame.nt.nameIdx
+	genExp(stmt.Iterator, ctx, 1)
+	ctx.setPos(stmt)
+	ctx.writeIns(proto.INS_ITER_NEW)
+	// iterator is saved in a variable which can not be referred by script
+	iter := startSize
+	ctx.insPushName("#iterator", uint32(line))
+	curSize := *ctx.frame.nt.nameIdx
+	ctx.frame.bs.pushFor(curSize, ctx.frame.curTryLevel)
+
+	p0 := ctx.textSize()
+	ctx.setPos(stmt)
+	ctx.insIterNext(iter)
+	p2ptr := ctx.insIterUnpack(iter, 0)
+	ctx.insPushName(stmt.Val, uint32(line))
+	if stmt.Key != "" {
+		ctx.insPushName(stmt.Key, uint32(line))
+	} else {
+		ctx.insPopTop()
+	}
+	if stmt.Const {
+		ctx.frame.nt.setConst(stmt.Val)
+		if stmt.Key != "" {
+			ctx.frame.nt.setConst(stmt.Key)
+		}
+	}
+	genBlockStmts(stmt.Block.Blocks, ctx)
+	p3 := ctx.textSize()
+	ctx.insResizeNameTable(curSize)
+	ctx.insJumpRel(p0)
+	p2 := ctx.textSize()
+	ctx.setSteps(p2ptr, p2)
+
+	ctx.leaveBlock(startSize, true)
+
+	fs := ctx.frame.bs.top().(*forBlock)
+	for i := range fs.breaks {
+		ctx.setSteps(fs.breaks[i], p2)
+	}
+	for i := range fs.continues {
+		ctx.setSteps(fs.continues[i], p3)
+	}
+	ctx.frame.bs.pop()
+}
+
 func genWhileStmt(stmt *ast.WhileStmt, ctx *Context) {
 	genForStmt(&ast.ForStmt{
 		Condition: stmt.Condition,
@@ -596,6 +883,9 @@ func genVarDeclStmt(stmt *ast.VarDeclStmt, ctx *Context) {
 	genExps(stmt.Rights, ctx, len(stmt.Lefts))
 	for i := len(stmt.Lefts) - 1; i >= 0; i-- {
 		ctx.insPushName(stmt.Lefts[i], uint32(stmt.Line))
+		if stmt.Const {
+			ctx.frame.nt.setConst(stmt.Lefts[i])
+		}
 	}
 }
 
@@ -605,28 +895,37 @@ func needRotate(op int) bool {
 }
 
 func genVarAssignStmt(stmt *ast.VarAssignStmt, ctx *Context) {
+	line := stmt.Line
+	if pos, ok := ctx.parser.Positions[stmt]; ok && line == 0 {
+		// i++ or ++i
+		line = pos.Line
+	}
 	genExps(stmt.Rights, ctx, len(stmt.Lefts))
 
 	for i := len(stmt.Lefts) - 1; i >= 0; i-- {
 		target := stmt.Lefts[i]
 		length := len(target.Attrs)
 		if length == 0 {
+			ctx.checkAssign(target.Prefix, line)
 			if stmt.AssignOp != ast.ASIGN_OP_ASSIGN {
-				genExp(&ast.NameExp{Name: target.Prefix}, ctx, 1)
+				genExp(&ast.NameExp{Line: line, Name: target.Prefix}, ctx, 1)
 				if needRotate(stmt.AssignOp) {
 					ctx.writeIns(proto.INS_ROT_TWO)
 				}
+				ctx.setPos(stmt)
 				ctx.writeIns(byte(stmt.AssignOp-ast.ASIGN_OP_ASSIGN) + proto.INS_BINARY_START)
 			}
-			ctx.insStoreName(target.Prefix, stmt.Line)
+			ctx.insStoreName(target.Prefix, line)
 			continue
 		}
 		genExp(target.Attrs[len(target.Attrs)-1], ctx, 1)
-		ctx.insLoadName(target.Prefix, stmt.Line)
+		ctx.insLoadName(target.Prefix, line)
 		for i := 0; i < length-1; i++ {
 			genExp(target.Attrs[i], ctx, 1)
+			ctx.setPos(stmt)
 			ctx.writeIns(proto.INS_BINARY_ATTR)
 		}
+		ctx.setPos(stmt)
 		ctx.writeIns(byte(stmt.AssignOp-ast.ASIGN_OP_START) + proto.INS_ATTR_ASSIGN_START)
 	}
 }
@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"fmt"
+	"gscript/compiler/ast"
 	"gscript/compiler/codegen"
 	"gscript/compiler/lexer"
 	"gscript/compiler/parser"
@@ -28,40 +29,83 @@ func readCode(path string) ([]byte, error) {
 	return ioutil.ReadFile(path)
 }
 
+// Host describes values provided by host application, they are referred by indexes
+type Host struct {
+	Globals map[string]uint32 // global name -> index of host value
+	Modules map[string]uint32 // module name -> index of host value
+}
+
 func ComplieWithSrcFile(path string) (protos []proto.Proto, err error) {
+	return ComplieWithHostFile(path, nil)
+}
+
+// ComplieWithSrcCode compiles code and all files imported by it. Syntax and semantic
+// errors of all files are returned together as ast.ErrorList.
+func ComplieWithSrcCode(code []byte, filename string) (protos []proto.Proto, err error) {
+	return ComplieWithHost(code, filename, nil)
+}
+
+func ComplieWithHostFile(path string, host *Host) (protos []proto.Proto, err error) {
 	code, err := readCode(path)
 	if err != nil {
 		return
 	}
-	return ComplieWithSrcCode(code, path)
+	return ComplieWithHost(code, path, host)
 }
 
-func ComplieWithSrcCode(code []byte, filename string) (protos []proto.Proto, err error) {
+// ComplieWithHost is like ComplieWithSrcCode, but code can refer to globals and modules
+// provided by host.
+func ComplieWithHost(code []byte, filename string, host *Host) (protos []proto.Proto, err error) {
+	if host == nil {
+		host = &Host{}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*ast.Error)
+			if !ok {
+				panic(r)
+			}
+			protos, err = nil, ast.ErrorList{e}
+		}
+	}()
 	graph := newGraph()
 	n := graph.insert(filename)
-	if err = complie(code, n, graph); err != nil {
+	var errs ast.ErrorList
+	if err = complie(code, n, graph, host, &errs); err != nil {
 		return
 	}
+	if err = errs.Err(); err != nil {
+		return nil, err
+	}
 	if graph.hasCircle() {
 		return nil, fmt.Errorf("import circle occurs")
 	}
 	return graph.sortProtos(), nil
 }
 
-func complie(code []byte, n *node, graph *graph) error {
+// complie file of n and files imported by it, syntax and semantic errors are added to errs
+func complie(code []byte, n *node, graph *graph, host *Host, errs *ast.ErrorList) error {
+	n.complied = true
 	parser := parser.NewParser(lexer.NewLexer(n.pathname, code))
-	prog := parser.Parse()
+	var prog *ast.Program
+	if !collectErrors(errs, func() { prog = parser.Parse() }) {
+		return nil
+	}
 
 	var imports []codegen.Import
 	var nodes []*node
 	for _, _import := range prog.Imports {
 		for _, lib := range _import.Libs {
 			var protoNumber uint32
+			var hostModule bool
 			if lib.Stdlib {
 				var err error
 				protoNumber, err = std.GetLibProtoNumByName(lib.Path)
 				if err != nil {
-					return err
+					if protoNumber, hostModule = host.Modules[lib.Path]; !hostModule {
+						errs.Add(&ast.Error{File: n.pathname, Line: _import.Line, Msg: err.Error()})
+						continue
+					}
 				}
 			} else {
 				nn := graph.insertPath(n.pathname, lib.Path+".gs")
@@ -77,26 +121,45 @@ func complie(code []byte, n *node, graph *graph) error {
 				ProtoNumber: protoNumber,
 				Alias:       alias,
 				StdLib:      lib.Stdlib,
+				Host:        hostModule,
 			})
 		}
 	}
 
-	proto := codegen.Gen(parser, prog, imports, n.protoNum)
-	proto.FilePath = n.pathname
-	n.proto = &proto
+	collectErrors(errs, func() {
+		proto := codegen.Gen(parser, prog, imports, n.protoNum, host.Globals)
+		proto.FilePath = n.pathname
+		n.proto = &proto
+	})
 
 	for _, n = range nodes {
 		// if file has been already complied
-		if n.proto != nil {
+		if n.complied {
 			continue
 		}
 		code, err := readCode(n.pathname)
 		if err != nil {
 			return err
 		}
-		if err := complie(code, n, graph); err != nil {
+		if err := complie(code, n, graph, host, errs); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// call f, errors panicked by it are added to errs. It returns false if f fails.
+func collectErrors(errs *ast.ErrorList, f func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			list, isList := r.(ast.ErrorList)
+			if !isList {
+				panic(r)
+			}
+			*errs = append(*errs, list...)
+			ok = false
+		}
+	}()
+	f()
+	return true
+}
@@ -2,8 +2,8 @@ package compiler
 
 import (
 	"fmt"
+	"gscript/compiler/ast"
 	"gscript/proto"
-	"os"
 	"path/filepath"
 )
 
@@ -17,6 +17,7 @@ type node struct {
 	color    uint32
 	protoNum uint32
 	proto    *proto.Proto
+	complied bool // complied before, maybe failed
 	pathname string
 	imports  []*node
 }
@@ -32,13 +33,11 @@ func newGraph() *graph {
 }
 
 func abs(path string) string {
-	path, err := filepath.Abs(path)
+	res, err := filepath.Abs(path)
 	if err != nil {
-		fmt.Printf("get absolute filepath of '%s' failed\n", path)
-		fmt.Printf("\terror message: %v\n", err)
-		os.Exit(0)
+		panic(&ast.Error{File: path, Msg: fmt.Sprintf("get absolute filepath failed: %v", err)})
 	}
-	return path
+	return res
 }
 
 func (g *graph) sortProtos() []proto.Proto {
@@ -76,22 +75,12 @@ func hasCircle(n *node) bool {
 	return false
 }
 
-func chdir(dir string) {
-	err := os.Chdir(dir)
-	if err == nil {
-		return
-	}
-	fmt.Printf("change work dir failed\n")
-	fmt.Printf("\terror message: %v\n", err)
-	os.Exit(0)
-}
-
+// path of _import relative to the directory of file base
 func getImportPath(base string, _import string) string {
-	curDir := abs(".")
-	chdir(filepath.Dir(base))
-	res := abs(_import)
-	chdir(curDir)
-	return res
+	if filepath.IsAbs(_import) {
+		return filepath.Clean(_import)
+	}
+	return filepath.Join(filepath.Dir(base), _import)
 }
 
 func (g *graph) insertPath(from, to string) *node {
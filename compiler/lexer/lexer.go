@@ -2,10 +2,12 @@ package lexer
 
 import (
 	"fmt"
+	"gscript/compiler/ast"
 	"gscript/compiler/token"
-	"os"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -24,6 +26,11 @@ type Lexer struct {
 	srcFile    string       // source file path
 	curToken   *token.Token // current token
 	aheadToken *token.Token // save LookAhead token temporarily
+	lastToken  *token.Token // token returned by NextToken or LookAhead most recently
+	depth      int          // nesting depth of curly brackets returned by NextToken
+	braces     int          // nesting depth of curly brackets scanned
+	templates  []int        // braces when interpolations of template strings start
+	errs       ast.ErrorList
 }
 
 func NewLexer(srcFile string, src []byte) *Lexer {
@@ -43,26 +50,65 @@ func (l *Lexer) Column() int {
 	return l.column
 }
 
+// Position returns line and column of the token returned by NextToken or LookAhead most
+// recently, where parser finds errors. End of file is right after the last token.
+func (l *Lexer) Position() (line, column int) {
+	t := l.lastToken
+	if t == nil {
+		return l.line, l.column + 1
+	}
+	if t.Kind != token.TOKEN_EOF {
+		return t.Line, t.Kth + 1
+	}
+	if t = l.curToken; t == nil {
+		return 1, 1
+	}
+	// the last token may span lines
+	line, column = t.Line, t.Kth+len(t.Content)
+	if i := strings.LastIndexByte(t.Content, CHAR_LF); i >= 0 {
+		line, column = line+strings.Count(t.Content, "\n"), len(t.Content)-i-1
+	}
+	return line, column + 1
+}
+
 func (l *Lexer) SrcFile() string {
 	return l.srcFile
 }
 
+// Depth returns nesting depth of curly brackets returned by NextToken
+func (l *Lexer) Depth() int {
+	return l.depth
+}
+
+// Errors returns errors found so far, invalid characters are skipped after being reported
+func (l *Lexer) Errors() ast.ErrorList {
+	return l.errs
+}
+
 // Look ahead 1 token
 func (l *Lexer) LookAhead() (token *token.Token) {
-	if l.aheadToken != nil {
-		return l.aheadToken
+	if l.aheadToken == nil {
+		l.aheadToken = l.nextToken()
 	}
-	l.aheadToken = l.nextToken()
+	l.lastToken = l.aheadToken
 	return l.aheadToken
 }
 
-func (l *Lexer) NextToken() (token *token.Token) {
+func (l *Lexer) NextToken() (t *token.Token) {
 	// if LookAhead before
 	if l.aheadToken != nil {
-		token, l.aheadToken = l.aheadToken, nil
-		return
+		t, l.aheadToken = l.aheadToken, nil
+	} else {
+		t = l.nextToken()
+	}
+	l.lastToken = t
+	switch t.Kind {
+	case token.TOKEN_SEP_LCURLY:
+		l.depth++
+	case token.TOKEN_SEP_RCURLY:
+		l.depth--
 	}
-	return l.nextToken()
+	return t
 }
 
 func (l *Lexer) nextToken() *token.Token {
@@ -86,6 +132,8 @@ again:
 		l.genToken(token.TOKEN_SEP_DOT, 1)
 	case '"', '\'':
 		l.scanStringLiteral()
+	case '`':
+		l.scanTemplate()
 	case ':':
 		l.genToken(token.TOKEN_SEP_COLON, 1)
 	case ';':
@@ -103,8 +151,16 @@ again:
 	case ')':
 		l.genToken(token.TOKEN_SEP_RPAREN, 1)
 	case '{':
+		l.braces++
 		l.genToken(token.TOKEN_SEP_LCURLY, 1)
 	case '}':
+		// end of interpolation, the template string goes on
+		if n := len(l.templates); n > 0 && l.templates[n-1] == l.braces {
+			l.templates = l.templates[:n-1]
+			l.scanTemplate()
+			break
+		}
+		l.braces--
 		l.genToken(token.TOKEN_SEP_RCURLY, 1)
 	case '+':
 		nextCh := l.lookAhead(1)
@@ -229,12 +285,17 @@ again:
 		l.skipComment()
 		goto again
 	default:
+		r, size := utf8.DecodeRune(l.src[l.cursor:])
 		if isDigit(curCh) {
 			l.scanNumber()
-		} else if isLetter_(curCh) {
+		} else if nextCh := l.lookAhead(1); curCh == 'r' && (nextCh == '"' || nextCh == '\'' || nextCh == '`') {
+			l.scanRawString()
+		} else if isLetter(r) {
 			l.scanIdentifier()
 		} else {
-			l.error("unexpected symbol near '%c'", curCh)
+			l.error("unexpected symbol near '%c'", r)
+			l.forward(size)
+			goto again
 		}
 	}
 
@@ -242,9 +303,15 @@ again:
 	return l.curToken
 }
 
+// identifiers consist of unicode letters, digits and _
 func (l *Lexer) scanIdentifier() {
-	k := l.cursor + 1
-	for ; k < len(l.src) && (isLetter_(l.src[k]) || isDigit(l.src[k])); k++ {
+	k := l.cursor
+	for k < len(l.src) {
+		r, size := utf8.DecodeRune(l.src[k:])
+		if !isLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		k += size
 	}
 
 	l.genToken(token.TOKEN_IDENTIFIER, k-l.cursor)
@@ -277,6 +344,9 @@ func (l *Lexer) scanNumber() {
 		} else if nextCh == 'x' { // hex
 			if gapCh := l.lookAhead(2); gapCh == CHAR_EOF || !isHexDigit(gapCh) {
 				l.error("invalid hex number near '%c'", firstDigit)
+				k += 2
+				value = int64(0)
+				goto end
 			}
 			base = 16
 			k += 2
@@ -310,6 +380,7 @@ func (l *Lexer) scanFloat(start int) (end int, value float64) {
 	value, err := strconv.ParseFloat(string(l.src[l.cursor:end]), 64)
 	if err != nil {
 		l.error(err.Error())
+		value = 0
 	}
 	return end, value
 }
@@ -320,13 +391,19 @@ func (l *Lexer) scanStringLiteral() {
 	var b strings.Builder
 	escape := false
 	start := l.cursor + 1 // skip first " or '
+	closed := true
 	for {
 		ahead := l.lookAhead(k)
 		if ahead == CHAR_EOF || ahead == CHAR_CR || ahead == CHAR_LF {
+			// the string ends at the end of line
 			l.error("expect another quotation mark before end of file or newline")
+			closed = false
+			break
 		}
 		if isQuoteAndUnmatched(ahead, curCh) {
 			l.error("expect another %c, but got %c", curCh, ahead)
+			k++
+			continue
 		}
 		if ahead == l.src[l.cursor] { //matched
 			break
@@ -338,32 +415,171 @@ func (l *Lexer) scanStringLiteral() {
 		gap := l.lookAhead(k + 1)
 		if gap == CHAR_EOF {
 			l.error("expect another quotation mark before end of file or newline")
-		}
-		// only support \n, \t, \', \", \\,
-		if gap != 'n' && gap != 't' && gap != '\'' && gap != '"' && gap != '\\' {
-			l.cursor += k
-			l.error("invalid escape character \\%c", gap)
+			closed = false
+			k++
+			break
 		}
 		escape = true
 		b.Write(l.src[start : l.cursor+k])
-		if gap == 'n' {
-			b.WriteByte('\n')
-		} else if gap == 't' {
-			b.WriteByte('\t')
-		} else {
-			b.WriteByte(gap)
-		}
-		k += 2
+		k += l.scanEscape(&b, k)
 		start = l.cursor + k
 	}
-	l.genToken(token.TOKEN_STRING, k+1)
+	length := k + 1
+	if !closed {
+		length = k
+	}
+	l.genToken(token.TOKEN_STRING, length)
 	if escape {
 		b.Write(l.src[start : l.cursor+k])
 		l.curToken.Value = b.String()
 	} else {
 		l.curToken.Value = string(l.src[l.cursor+1 : l.cursor+k])
 	}
-	l.forward(k)
+	l.forward(length - 1)
+}
+
+// scan a part of template string starting at ` or } ending an interpolation. Template strings
+// can span lines, and \` and \$ are escaped besides escape sequences of strings.
+func (l *Lexer) scanTemplate() {
+	var b strings.Builder
+	kind := token.TOKEN_STRING
+	head := l.src[l.cursor] == '`'
+	if !head {
+		kind = token.TOKEN_TEMPLATE_TAIL
+	}
+	k := 1
+	for {
+		ahead := l.lookAhead(k)
+		if ahead == CHAR_EOF {
+			l.error("expect another ` before end of file")
+			break
+		}
+		if ahead == '`' {
+			k++
+			break
+		}
+		if ahead == '$' && l.lookAhead(k+1) == '{' {
+			k += 2
+			kind = token.TOKEN_TEMPLATE_MIDDLE
+			if head {
+				kind = token.TOKEN_TEMPLATE_HEAD
+			}
+			l.templates = append(l.templates, l.braces)
+			break
+		}
+		gap := l.lookAhead(k + 1)
+		if ahead == '\\' && (gap == '`' || gap == '$') {
+			b.WriteByte(gap)
+			k += 2
+		} else if ahead == '\\' && gap != CHAR_EOF {
+			k += l.scanEscape(&b, k)
+		} else if ahead == CHAR_CR && gap == CHAR_LF {
+			// \r\n is converted to \n
+			k++
+		} else {
+			b.WriteByte(ahead)
+			k++
+		}
+	}
+	l.genStringToken(kind, k, b.String())
+}
+
+// raw strings are prefixed with r, escape sequences are not processed in them. Raw strings
+// quoted by ` can span lines.
+func (l *Lexer) scanRawString() {
+	quote := l.lookAhead(1)
+	k := 2
+	closed := true
+	for ; ; k++ {
+		ahead := l.lookAhead(k)
+		if ahead == quote {
+			break
+		}
+		if ahead == CHAR_EOF || quote != '`' && (ahead == CHAR_CR || ahead == CHAR_LF) {
+			l.error("expect another %c before end of file or newline", quote)
+			closed = false
+			break
+		}
+	}
+	value := strings.ReplaceAll(string(l.src[l.cursor+2:l.cursor+k]), "\r\n", "\n")
+	if closed {
+		k++
+	}
+	l.genStringToken(token.TOKEN_STRING, k, value)
+}
+
+// generate token of string which may span lines, line and column are moved to the end of it
+func (l *Lexer) genStringToken(kind, contentLength int, value string) {
+	l.genToken(kind, contentLength)
+	l.curToken.Value = value
+	content := l.curToken.Content
+	i := strings.LastIndexByte(content, CHAR_LF)
+	if i < 0 {
+		l.forward(contentLength - 1)
+		return
+	}
+	l.line += strings.Count(content, "\n")
+	l.cursor += contentLength - 1
+	l.column = contentLength - 2 - i
+}
+
+// write the character of escape sequence whose backslash is @k characters ahead, returns length
+// of the sequence. Supports \n, \t, \r, \0, \', \", \\, \xHH and \u{H...}, invalid ones are kept as
+// the character after backslash.
+func (l *Lexer) scanEscape(b *strings.Builder, k int) int {
+	gap := l.lookAhead(k + 1)
+	switch gap {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case '0':
+		b.WriteByte(0)
+	case '\'', '"', '\\':
+		b.WriteByte(gap)
+	case 'x':
+		// character of code point HH rather than a single byte, so that strings are valid UTF-8
+		hi, lo := l.lookAhead(k+2), l.lookAhead(k+3)
+		if !isHexDigit(hi) || !isHexDigit(lo) {
+			l.error("invalid escape sequence, expect \\x followed by 2 hex digits")
+			b.WriteByte(gap)
+			break
+		}
+		b.WriteRune(rune(toNumber(hi)<<4 | toNumber(lo)))
+		return 4
+	case 'u':
+		// code point of 1 to 6 hex digits
+		i := k + 2
+		if l.lookAhead(i) != '{' {
+			l.error("invalid escape sequence, expect \\u{hex digits}")
+			b.WriteByte(gap)
+			break
+		}
+		var code int64
+		for i++; isHexDigit(l.lookAhead(i)); i++ {
+			if code <= unicode.MaxRune {
+				code = code<<4 | toNumber(l.lookAhead(i))
+			}
+		}
+		if digits := i - k - 3; digits == 0 || l.lookAhead(i) != '}' {
+			l.error("invalid escape sequence, expect \\u{hex digits}")
+			b.WriteByte(gap)
+			break
+		}
+		if code > unicode.MaxRune || code >= 0xD800 && code <= 0xDFFF {
+			l.error("invalid unicode code point %s", l.src[l.cursor+k:l.cursor+i+1])
+			code = utf8.RuneError
+		}
+		b.WriteRune(rune(code))
+		return i - k + 1
+	default:
+		r, _ := utf8.DecodeRune(l.src[l.cursor+k+1:])
+		l.error("invalid escape character \\%c", r)
+		b.WriteByte(gap)
+	}
+	return 2
 }
 
 func (l *Lexer) skipComment() {
@@ -423,11 +639,14 @@ func (l *Lexer) lookAhead(k int) byte {
 	return l.src[idx]
 }
 
+// report an error at current position, lexer goes on after it
 func (l *Lexer) error(format string, args ...interface{}) {
-	errMsg := fmt.Sprintf(format, args...)
-	fmt.Printf("Lexer error: [%s:%d:%d]\n", l.srcFile, l.line, l.column+1)
-	fmt.Printf("\t%s\n", errMsg)
-	os.Exit(0)
+	l.errs.Add(&ast.Error{
+		File:   l.srcFile,
+		Line:   l.line,
+		Column: l.column + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	})
 }
 
 // move cursor and kth @k steps
@@ -461,8 +680,8 @@ func isHexDigit(ch byte) bool {
 	return ch <= '9' && ch >= '0' || ch <= 'f' && ch >= 'a' || ch <= 'F' && ch >= 'A'
 }
 
-func isLetter_(ch byte) bool {
-	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+func isLetter(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= utf8.RuneSelf && unicode.IsLetter(r)
 }
 
 func toNumber(digit byte) (result int64) {
@@ -476,6 +695,7 @@ var addSemiTokens = map[int]struct{}{
 	token.TOKEN_IDENTIFIER:     {},
 	token.TOKEN_NUMBER:         {},
 	token.TOKEN_STRING:         {},
+	token.TOKEN_TEMPLATE_TAIL:  {},
 	token.TOKEN_KW_BREAK:       {},
 	token.TOKEN_KW_FALLTHROUGH: {},
 	token.TOKEN_KW_CONTINUE:    {},
@@ -293,6 +293,12 @@ var STRING_TOKEN_MAP = map[string]string{
 	`'\\'`:             `\`,
 	`'\\\\'`:           `\\`,
 	`'\\\"'`:           `\"`,
+	`"a\r\n"`:          "a\r\n",
+	`"\0"`:             "\x00",
+	`"\x41\xe9"`:       "Aé",
+	`"\u{4f60}好"`:      "你好",
+	`'\u{1F600}!'`:     "\U0001F600!",
+	`"你好"`:             "你好",
 }
 
 var IDENTIFIER_TOKEN_MAP = map[string]bool{
@@ -303,4 +309,61 @@ var IDENTIFIER_TOKEN_MAP = map[string]bool{
 	"a123":      true,
 	"a1_23":     true,
 	"a1_23_bc8": true,
+	"变量":        true,
+	"_π2":       true,
+	"café":      true,
+}
+
+func TestTemplateString(t *testing.T) {
+	src := "`a\\`${x}b\r\n${ {} }\\${c}` r`\\n\n` r'\\t'\nd"
+	l := NewLexer("", []byte(src))
+	wants := []struct {
+		kind  int
+		value interface{}
+	}{
+		{TOKEN_TEMPLATE_HEAD, "a`"},
+		{TOKEN_IDENTIFIER, "x"},
+		{TOKEN_TEMPLATE_MIDDLE, "b\n"},
+		{TOKEN_SEP_LCURLY, nil},
+		{TOKEN_SEP_RCURLY, nil},
+		{TOKEN_TEMPLATE_TAIL, "${c}"},
+		{TOKEN_STRING, "\\n\n"},
+		{TOKEN_STRING, "\\t"},
+		{TOKEN_SEP_SEMI, nil},
+		{TOKEN_IDENTIFIER, "d"},
+	}
+	for _, want := range wants {
+		tk := l.NextToken()
+		if tk.Kind != want.kind || want.value != nil && tk.Value != want.value {
+			t.Fatalf("want token %v of kind %d, but got %v of kind %d", want.value, want.kind, tk.Value, tk.Kind)
+		}
+	}
+	if l.Line() != 4 {
+		t.Fatalf("want line 4 at the end, but got %d", l.Line())
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	src := "a $ b\n\"abc\nc\n'x\\qy'\n€ '\\u{110000}\\x4'"
+	l := NewLexer("", []byte(src))
+	var got []string
+	for tk := l.NextToken(); tk.Kind != TOKEN_EOF; tk = l.NextToken() {
+		if tk.Kind != TOKEN_SEP_SEMI {
+			got = append(got, fmt.Sprint(tk.Value))
+		}
+	}
+	want := []string{"a", "b", "abc", "c", "xqy", "\uFFFDx4"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("want tokens %v, but got %v", want, got)
+	}
+	errs := l.Errors()
+	lines := []int{1, 2, 4, 5, 5, 5}
+	if len(errs) != len(lines) {
+		t.Fatalf("want %d errors, but got %v", len(lines), errs)
+	}
+	for i, line := range lines {
+		if errs[i].Line != line {
+			t.Fatalf("want error at line %d, but got %v", line, errs[i])
+		}
+	}
 }
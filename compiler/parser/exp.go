@@ -25,14 +25,14 @@ term9  ::= term8  { '|' term8 }
 term8  ::= term7  { '^' term7 }
 term7  ::= term6  { '&' term6 }
 term6  ::= term5  { ('==' | '!=') term5 }
-term5  ::= term4  { ( '>' | '<' | '>=' | '<=') term4 }
+term5  ::= term4  { ( '>' | '<' | '>=' | '<=' | 'instanceof') term4 }
 term4  ::= term3  { ( '<<' | '>>' ) term3 }
 term3  ::= term2  { ( '+' | '-') term2 }
 term2  ::= term1  { ( '/' | '*' | '%' | '//' ) term1 }
 term1  ::= { ( '-' | '~  | '!' ) } term0
 term0  ::= factor | ( '++' | '--') factor | factor ('++' | '--')
-factor ::= '(' exp ')' | literal | nil | new ID ['(' [expList] ')']
-		 | ID { ( '.' ID | '[' exp ']' | '(' [expList] ')' ) }
+factor ::= primary { ( '.' ID | '[' exp ']' | '(' [expList] ')' ) }
+primary ::= '(' exp ')' | literal | template | nil | new ID ['(' [expList] ')'] | ID
 */
 
 // '(' [explist] ')
@@ -100,7 +100,7 @@ func parseTerm6(p *Parser) ast.Exp {
 }
 
 func parseTerm5(p *Parser) ast.Exp {
-	return _parseBinExp(p, []int{token.TOKEN_OP_LE, token.TOKEN_OP_GE, token.TOKEN_OP_LT, token.TOKEN_OP_GT}, parseTerm4)
+	return _parseBinExp(p, []int{token.TOKEN_OP_LE, token.TOKEN_OP_GE, token.TOKEN_OP_LT, token.TOKEN_OP_GT, token.TOKEN_KW_INSTANCEOF}, parseTerm4)
 }
 
 func parseTerm4(p *Parser) ast.Exp {
@@ -116,7 +116,8 @@ func parseTerm2(p *Parser) ast.Exp {
 }
 
 func parseTerm1(p *Parser) ast.Exp {
-	kind := p.l.LookAhead().Kind
+	ahead := p.l.LookAhead()
+	kind := ahead.Kind
 
 	var unOp int
 	if kind == token.TOKEN_OP_SUB {
@@ -129,34 +130,48 @@ func parseTerm1(p *Parser) ast.Exp {
 		return parseTerm0(p)
 	}
 	p.l.NextToken()
-	return &ast.UnOpExp{Op: unOp, Exp: parseTerm1(p)}
+	exp := &ast.UnOpExp{Op: unOp, Exp: parseTerm1(p)}
+	p.mark(exp, ahead)
+	return exp
 }
 
 func parseTerm0(p *Parser) ast.Exp {
+	var unOpExp *ast.UnOpExp
 	if p.Expect(token.TOKEN_OP_INC) {
-		p.l.NextToken()
-		return &ast.UnOpExp{Op: ast.UNOP_INC, Exp: parseFactor(p)}
+		t := p.l.NextToken()
+		unOpExp = &ast.UnOpExp{Op: ast.UNOP_INC, Exp: parseFactor(p)}
+		p.mark(unOpExp, t)
+		return unOpExp
 	} else if p.Expect(token.TOKEN_OP_DEC) {
-		p.l.NextToken()
-		return &ast.UnOpExp{Op: ast.UNOP_DEC, Exp: parseFactor(p)}
+		t := p.l.NextToken()
+		unOpExp = &ast.UnOpExp{Op: ast.UNOP_DEC, Exp: parseFactor(p)}
+		p.mark(unOpExp, t)
+		return unOpExp
 	}
 	exp := parseFactor(p)
 	if p.Expect(token.TOKEN_OP_INC) {
-		p.l.NextToken()
-		return &ast.UnOpExp{Op: ast.UNOP_INC_, Exp: exp}
+		unOpExp = &ast.UnOpExp{Op: ast.UNOP_INC_, Exp: exp}
 	} else if p.Expect(token.TOKEN_OP_DEC) {
-		p.l.NextToken()
-		return &ast.UnOpExp{Op: ast.UNOP_DEC_, Exp: exp}
+		unOpExp = &ast.UnOpExp{Op: ast.UNOP_DEC_, Exp: exp}
+	} else {
+		return exp
 	}
-	return exp
+	p.mark(unOpExp, p.l.NextToken())
+	return unOpExp
 }
 
-// factor ::= '(' exp ')' | literal | nil | new ID ['(' [expList] ')']
-// 		 | ID { ( '.' ID | '[' exp ']' | '(' [expList] ')' ) }
+// factor ::= primary { ( '.' ID | '[' exp ']' | '(' [expList] ')' ) }
 func parseFactor(p *Parser) ast.Exp {
+	return parseSuffixes(p, parsePrimary(p))
+}
+
+// primary ::= '(' exp ')' | literal | template | nil | new ID ['(' [expList] ')'] | ID
+func parsePrimary(p *Parser) ast.Exp {
 	switch ahead := p.l.LookAhead(); ahead.Kind {
 	case token.TOKEN_STRING:
 		return parseStringLiteralExp(p)
+	case token.TOKEN_TEMPLATE_HEAD:
+		return parseTemplateExp(p)
 	case token.TOKEN_NUMBER:
 		return parseNumberLiteralExp(p)
 	case token.TOKEN_SEP_LCURLY: // mapLiteral
@@ -174,7 +189,7 @@ func parseFactor(p *Parser) ast.Exp {
 	case token.TOKEN_KW_NEW:
 		return parseNewObjectExp(p)
 	case token.TOKEN_IDENTIFIER:
-		return parseFuncCallOrAttrExp(p)
+		return parseNameExp(p)
 	case token.TOKEN_SEP_LPAREN: // (exp)
 		p.l.NextToken()
 		exp := parseExp(p)
@@ -214,14 +229,42 @@ func parseStringLiteralExp(p *Parser) *ast.StringLiteralExp {
 	return &ast.StringLiteralExp{Value: p.l.NextToken().Value.(string)}
 }
 
-func parseFuncCallOrAttrExp(p *Parser) ast.Exp {
-	var exp ast.Exp
-	exp = &ast.NameExp{
-		Line: p.l.Line(),
-		Name: p.l.NextToken().Content,
+// template ::= TEMPLATE_HEAD exp { TEMPLATE_MIDDLE exp } TEMPLATE_TAIL
+func parseTemplateExp(p *Parser) *ast.TemplateExp {
+	head := p.l.NextToken()
+	exp := &ast.TemplateExp{}
+	for t := head; ; {
+		if str := t.Value.(string); str != "" {
+			exp.Parts = append(exp.Parts, &ast.StringLiteralExp{Value: str})
+		}
+		if t.Kind == token.TOKEN_TEMPLATE_TAIL {
+			break
+		}
+		exp.Parts = append(exp.Parts, parseExp(p))
+		if t = p.l.NextToken(); t.Kind != token.TOKEN_TEMPLATE_MIDDLE && t.Kind != token.TOKEN_TEMPLATE_TAIL {
+			p.exit("expect '}' after interpolation of template string, but got '%s'", t.Content)
+		}
 	}
+	p.mark(exp, head)
+	return exp
+}
+
+func parseNameExp(p *Parser) *ast.NameExp {
+	t := p.l.NextToken()
+	exp := &ast.NameExp{Line: t.Line, Name: t.Content}
+	p.mark(exp, t)
+	return exp
+}
+
+func parseFuncCallOrAttrExp(p *Parser) ast.Exp {
+	return parseSuffixes(p, parseNameExp(p))
+}
+
+// attribute accesses and function calls following exp, such as a.b[c](d)
+func parseSuffixes(p *Parser, exp ast.Exp) ast.Exp {
 	for {
-		switch p.l.LookAhead().Kind {
+		ahead := p.l.LookAhead()
+		switch ahead.Kind {
 		case token.TOKEN_SEP_DOT: // access attribute
 			p.l.NextToken()
 			exp = &ast.BinOpExp{
@@ -244,12 +287,13 @@ func parseFuncCallOrAttrExp(p *Parser) ast.Exp {
 		default:
 			return exp
 		}
+		p.mark(exp, ahead)
 	}
 }
 
 func parseNewObjectExp(p *Parser) *ast.NewObjectExp {
-	p.l.NextToken()
 	exp := &ast.NewObjectExp{}
+	p.mark(exp, p.l.NextToken())
 	exp.Name = p.NextTokenKind(token.TOKEN_IDENTIFIER).Content
 	exp.Line = p.l.Line()
 	if !p.Expect(token.TOKEN_SEP_LPAREN) {
@@ -321,11 +365,16 @@ func _parseBinExp(p *Parser, expect []int, cb func(*Parser) ast.Exp) ast.Exp {
 		if !flag {
 			return exp
 		}
+		op := p.l.NextToken()
 		binExp := &ast.BinOpExp{
 			Exp1:  exp,
-			BinOp: p.l.NextToken().Kind,
+			BinOp: op.Kind,
 			Exp2:  cb(p),
 		}
+		if op.Kind == token.TOKEN_KW_INSTANCEOF {
+			binExp.BinOp = ast.BINOP_INSTANCEOF
+		}
+		p.mark(binExp, op)
 		exp = binExp
 	}
 }
@@ -368,6 +368,28 @@ func TestParseAttributeAccessExp(t *testing.T) {
 	}
 }
 
+// "abc".len() (1)[0] ...
+func TestParseFactorSuffixes(t *testing.T) {
+	var srcs = []string{
+		`"abc".len()`,
+		`(n).toString(2)`,
+		`[1][0]`,
+	}
+	var wants = []Exp{
+		&FuncCallExp{&BinOpExp{BINOP_ATTR, &StringLiteralExp{"abc"}, &StringLiteralExp{"len"}}, nil},
+		&FuncCallExp{&BinOpExp{BINOP_ATTR, &NameExp{1, "n"}, &StringLiteralExp{"toString"}},
+			[]Exp{&NumberLiteralExp{int64(2)}}},
+		&BinOpExp{BINOP_ATTR, &ArrLiteralExp{[]Exp{&NumberLiteralExp{int64(1)}}}, &NumberLiteralExp{int64(0)}},
+	}
+	for i, src := range srcs {
+		l := newLexer(src)
+		exp := parseFactor(NewParser(l))
+		if !reflect.DeepEqual(wants[i], exp) {
+			t.Fatalf("parse suffixes of factor failed:\n%s\n", src)
+		}
+	}
+}
+
 func TestParseArrLiteralExp(t *testing.T) {
 	var arrLiterals = []string{
 		`[]`,
@@ -458,6 +480,31 @@ func TestNewObjectExp(t *testing.T) {
 	}
 }
 
+func TestParseTemplateExp(t *testing.T) {
+	var srcs = []string{
+		"`a${b}`",
+		"`${a + 1}-${ {b: 1} }\nc`",
+		"`${`${a}`}`",
+	}
+	var wants = []*TemplateExp{
+		{[]Exp{&StringLiteralExp{"a"}, &NameExp{1, "b"}}},
+		{[]Exp{
+			&BinOpExp{BINOP_ADD, &NameExp{1, "a"}, &NumberLiteralExp{int64(1)}},
+			&StringLiteralExp{"-"},
+			&MapLiteralExp{[]interface{}{"b"}, []Exp{&NumberLiteralExp{int64(1)}}},
+			&StringLiteralExp{"\nc"},
+		}},
+		{[]Exp{&TemplateExp{[]Exp{&NameExp{1, "a"}}}}},
+	}
+	for i, src := range srcs {
+		l := newLexer(src)
+		exp := parseTemplateExp(NewParser(l))
+		if !reflect.DeepEqual(exp, wants[i]) {
+			t.Fatalf("parse template string failed:\n%s\n", src)
+		}
+	}
+}
+
 func TestParseFuncLiteralExp(t *testing.T) {
 	var funcLiterals = []string{
 		`func(){}`,
@@ -491,3 +538,40 @@ func TestParseFuncLiteralExp(t *testing.T) {
 		}
 	}
 }
+
+func TestExpPositions(t *testing.T) {
+	srcs := []string{
+		`a + b`,
+		`obj.foo(2)`,
+		`  -a`,
+		`a.b++`,
+	}
+	wants := []Pos{{1, 3}, {1, 8}, {1, 3}, {1, 4}}
+
+	for i, src := range srcs {
+		p := NewParser(newLexer(src))
+		exp := parseExp(p)
+		if got := p.Positions[exp]; got != wants[i] {
+			t.Fatalf("position of expression failed:\n%s\nwant %v, but got %v\n", src, wants[i], got)
+		}
+	}
+}
+
+func TestParseExp(t *testing.T) {
+	exp := NewParser(newLexer(`a + b;`)).ParseExp()
+	want := &BinOpExp{BINOP_ADD, &NameExp{1, "a"}, &NameExp{1, "b"}}
+	if !reflect.DeepEqual(want, exp) {
+		t.Fatalf("parse expression failed")
+	}
+
+	for _, src := range []string{`a = 1`, `let a = 1`, `a b`} {
+		func() {
+			defer func() {
+				if _, ok := recover().(ErrorList); !ok {
+					t.Fatalf("%s should not be parsed as an expression", src)
+				}
+			}()
+			NewParser(newLexer(src)).ParseExp()
+		}()
+	}
+}
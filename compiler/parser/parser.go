@@ -5,7 +5,6 @@ import (
 	"gscript/compiler/ast"
 	"gscript/compiler/lexer"
 	"gscript/compiler/token"
-	"os"
 )
 
 type Parser struct {
@@ -13,23 +12,39 @@ type Parser struct {
 	EnumStmts  []*ast.EnumStmt
 	ClassStmts []*ast.ClassStmt
 	FuncDefs   []*ast.FuncDefStmt
+	Positions  map[interface{}]ast.Pos // statement or expression -> position in source file
+	errs       ast.ErrorList
 }
 
 func NewParser(l *lexer.Lexer) *Parser {
 	return &Parser{
-		l: l,
+		l:         l,
+		Positions: make(map[interface{}]ast.Pos),
 	}
 }
 
-func (p *Parser) Parse() *ast.Program {
-	program := p.parseProgram()
+// Parse parses the whole source file. Invalid statements are skipped after errors are
+// recorded, and all errors found by lexer and parser are panicked as ast.ErrorList at last.
+func (p *Parser) Parse() (program *ast.Program) {
+	defer p.checkErrors()
+	program = p.parseProgram()
 	if !p.Expect(token.TOKEN_EOF) {
-		fmt.Println("statement after export is not allowed!!!")
-		os.Exit(0)
+		p.exit("statement after export is not allowed")
 	}
 	return program
 }
 
+// ParseExp parses source code consisting of a single expression, e.g. input of REPL
+func (p *Parser) ParseExp() (exp ast.Exp) {
+	defer p.checkErrors()
+	exp = parseExp(p)
+	p.ConsumeIf(token.TOKEN_SEP_SEMI)
+	if !p.Expect(token.TOKEN_EOF) {
+		p.exit("unexpected token '%s' after expression", p.l.LookAhead().Content)
+	}
+	return exp
+}
+
 func (p *Parser) NextTokenKind(kind int) *token.Token {
 	t := p.l.NextToken()
 	if t.Kind != kind {
@@ -51,9 +66,90 @@ func (p *Parser) Expect(kind int) bool {
 	return p.l.LookAhead().Kind == kind
 }
 
+// record position of token t for node, node should be a pointer
+func (p *Parser) mark(node interface{}, t *token.Token) {
+	p.Positions[node] = ast.Pos{Line: t.Line, Column: t.Kth + 1}
+}
+
+// panic errors found by lexer and parser as ast.ErrorList if any
+func (p *Parser) checkErrors() {
+	if r := recover(); r != nil {
+		err, ok := r.(*ast.Error)
+		if !ok {
+			panic(r)
+		}
+		p.errs.Add(err)
+	}
+	var errs ast.ErrorList
+	errs = append(errs, p.l.Errors()...)
+	errs = append(errs, p.errs...)
+	if len(errs) > 0 {
+		errs.Sort()
+		panic(errs)
+	}
+}
+
+// call parse, which parses a statement. If the statement is invalid, the error is recorded
+// and tokens are skipped until the end of the statement, so parsing can go on.
+func (p *Parser) try(parse func()) {
+	start, depth := p.l.LookAhead(), p.l.Depth()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		err, ok := r.(*ast.Error)
+		if !ok {
+			panic(r)
+		}
+		p.errs.Add(err)
+		// make progress
+		if p.l.LookAhead() == start && start.Kind != token.TOKEN_EOF {
+			p.l.NextToken()
+		}
+		p.skipStmt(depth)
+	}()
+	parse()
+}
+
+// skip tokens until the end of statement started at nesting depth of curly brackets
+func (p *Parser) skipStmt(depth int) {
+	for {
+		switch p.l.LookAhead().Kind {
+		case token.TOKEN_EOF:
+			return
+		case token.TOKEN_SEP_SEMI:
+			if p.l.Depth() <= depth {
+				p.l.NextToken()
+				return
+			}
+		case token.TOKEN_SEP_RCURLY:
+			// end of the enclosing block
+			if p.l.Depth() <= depth {
+				return
+			}
+		}
+		p.l.NextToken()
+	}
+}
+
+// report an error at the token being parsed
 func (p *Parser) exit(format string, args ...interface{}) {
-	errMsg := fmt.Sprintf(format, args...)
-	fmt.Printf("Parser error: [%s:%d:%d]\n", p.l.SrcFile(), p.l.Line(), p.l.Column())
-	fmt.Printf("\t%s\n", errMsg)
-	os.Exit(0)
+	line, column := p.l.Position()
+	panic(&ast.Error{
+		File:   p.l.SrcFile(),
+		Line:   line,
+		Column: column,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// report an error at token t, e.g. the start of a statement
+func (p *Parser) exitAt(t *token.Token, format string, args ...interface{}) {
+	panic(&ast.Error{
+		File:   p.l.SrcFile(),
+		Line:   t.Line,
+		Column: t.Kth + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	})
 }
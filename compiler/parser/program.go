@@ -18,8 +18,10 @@ func (p *Parser) parseImports() []ast.Import {
 	var imports []ast.Import
 
 	for p.Expect(token.TOKEN_KW_IMPORT) {
-		imports = append(imports, p.parseImport())
-		p.ConsumeIf(token.TOKEN_SEP_SEMI)
+		p.try(func() {
+			imports = append(imports, p.parseImport())
+			p.ConsumeIf(token.TOKEN_SEP_SEMI)
+		})
 	}
 	return imports
 }
@@ -66,13 +68,24 @@ func (p *Parser) parseImport() (ipt ast.Import) {
 func (p *Parser) parseBlockStmts(atTop bool) []ast.BlockStmt {
 	var blockStmts []ast.BlockStmt
 	for {
-		switch p.l.LookAhead().Kind {
+		switch ahead := p.l.LookAhead(); ahead.Kind {
 		case token.TOKEN_SEP_LCURLY:
-			blockStmts = append(blockStmts, p.parseBlock())
-		case token.TOKEN_KW_EXPORT, token.TOKEN_EOF, token.TOKEN_KW_CASE, token.TOKEN_KW_DEFAULT, token.TOKEN_SEP_RCURLY:
+			p.try(func() {
+				blockStmts = append(blockStmts, p.parseBlock())
+			})
+		case token.TOKEN_KW_EXPORT, token.TOKEN_EOF:
 			return blockStmts
+		case token.TOKEN_KW_CASE, token.TOKEN_KW_DEFAULT, token.TOKEN_SEP_RCURLY:
+			if !atTop {
+				return blockStmts
+			}
+			p.try(func() {
+				p.exit("unexpected token '%s'", ahead.Content)
+			})
 		default:
-			blockStmts = append(blockStmts, p.parseStmt(atTop))
+			p.try(func() {
+				blockStmts = append(blockStmts, p.parseStmt(atTop))
+			})
 		}
 	}
 }
@@ -29,3 +29,33 @@ import "../crawler",socks as craw,s`,
 		}
 	}
 }
+
+func TestParseErrors(t *testing.T) {
+	src := `
+let a = * 2
+print(a)
+func f() {
+	let b = (
+	return b
+}
+if (a { }
+let c = 2 $ 3
+}
+`
+	defer func() {
+		errs, ok := recover().(ErrorList)
+		if !ok {
+			t.Fatalf("want ErrorList")
+		}
+		lines := []int{2, 6, 8, 9, 9, 10}
+		if len(errs) != len(lines) {
+			t.Fatalf("want %d errors, but got:\n%v", len(lines), errs)
+		}
+		for i, line := range lines {
+			if errs[i].Line != line {
+				t.Fatalf("want error at line %d, but got:\n%v", line, errs)
+			}
+		}
+	}()
+	NewParser(newLexer(src)).Parse()
+}
@@ -9,7 +9,7 @@ func (p *Parser) parseStmt(atTop bool) ast.Stmt {
 	var stmt ast.Stmt
 	ahead := p.l.LookAhead()
 	switch ahead.Kind {
-	case token.TOKEN_KW_LET:
+	case token.TOKEN_KW_LET, token.TOKEN_KW_CONST:
 		stmt = p.parseVarDeclStmt()
 	case token.TOKEN_IDENTIFIER:
 		stmt = p.parseVarOpOrLabel()
@@ -51,6 +51,7 @@ func (p *Parser) parseStmt(atTop bool) ast.Stmt {
 	default:
 		p.exit("unexpected token '%s' to make a statement", ahead.Content)
 	}
+	p.mark(stmt, ahead)
 	p.ConsumeIf(token.TOKEN_SEP_SEMI)
 	return stmt
 }
@@ -60,16 +61,23 @@ func (p *Parser) parseTryCatchStmt() (stmt *ast.TryCatchStmt) {
 	p.l.NextToken()
 	p.ConsumeIf(token.TOKEN_SEP_SEMI)
 	stmt.TryBlocks = p.parseBlock().Blocks
-	p.NextTokenKind(token.TOKEN_KW_CATCH)
-	if p.ConsumeIf(token.TOKEN_SEP_LPAREN) {
-		if p.Expect(token.TOKEN_IDENTIFIER) {
-			stmt.CatchLine = p.l.Line()
-			stmt.CatchValue = p.l.NextToken().Content
+	if !p.Expect(token.TOKEN_KW_FINALLY) {
+		p.NextTokenKind(token.TOKEN_KW_CATCH)
+		stmt.HasCatch = true
+		if p.ConsumeIf(token.TOKEN_SEP_LPAREN) {
+			if p.Expect(token.TOKEN_IDENTIFIER) {
+				stmt.CatchLine = p.l.Line()
+				stmt.CatchValue = p.l.NextToken().Content
+			}
+			p.NextTokenKind(token.TOKEN_SEP_RPAREN)
 		}
-		p.NextTokenKind(token.TOKEN_SEP_RPAREN)
+		p.ConsumeIf(token.TOKEN_SEP_SEMI)
+		stmt.CatchBlocks = p.parseBlock().Blocks
+	}
+	if p.ConsumeIf(token.TOKEN_KW_FINALLY) {
+		p.ConsumeIf(token.TOKEN_SEP_SEMI)
+		stmt.FinallyBlocks = p.parseBlock().Blocks
 	}
-	p.ConsumeIf(token.TOKEN_SEP_SEMI)
-	stmt.CatchBlocks = p.parseBlock().Blocks
 	return stmt
 }
 
@@ -83,10 +91,14 @@ func toVarDeclStmt(stmt *ast.FuncDefStmt) ast.Stmt {
 // varDeclStmt ::= let id {,id} = exp {,exp} ;
 func (p *Parser) parseVarDeclStmt() (stmt *ast.VarDeclStmt) {
 	stmt = new(ast.VarDeclStmt)
-	p.l.NextToken()
-	stmt.Line = p.l.Line()
+	t := p.l.NextToken()
+	stmt.Const = t.Kind == token.TOKEN_KW_CONST
+	stmt.Line = t.Line
 	stmt.Lefts = p.parseNameList() // id {,id}
 	if !p.Expect(token.TOKEN_OP_ASSIGN) {
+		if stmt.Const {
+			p.exitAt(t, "missing value of constant '%s'", stmt.Lefts[0])
+		}
 		stmt.Rights = make([]ast.Exp, len(stmt.Lefts))
 		for i := range stmt.Rights {
 			stmt.Rights[i] = &ast.NilExp{}
@@ -253,6 +265,9 @@ func (p *Parser) parseClassStmt() (stmt *ast.ClassStmt) {
 	stmt = new(ast.ClassStmt)
 	p.l.NextToken()
 	stmt.Name = p.NextTokenKind(token.TOKEN_IDENTIFIER).Content
+	if p.ConsumeIf(token.TOKEN_KW_EXTENDS) {
+		stmt.Parent = parseExp(p)
+	}
 	p.ConsumeIf(token.TOKEN_SEP_SEMI)
 	p.NextTokenKind(token.TOKEN_SEP_LCURLY)
 
@@ -339,10 +354,12 @@ func (p *Parser) parseForStmt() (stmt *ast.ForStmt) {
 	p.NextTokenKind(token.TOKEN_SEP_LPAREN) // (
 
 	switch ahead := p.l.LookAhead(); ahead.Kind {
-	case token.TOKEN_KW_LET:
+	case token.TOKEN_KW_LET, token.TOKEN_KW_CONST:
 		stmt.DeclStmt = p.parseVarDeclStmt()
+		p.mark(stmt.DeclStmt, ahead)
 	case token.TOKEN_IDENTIFIER:
 		stmt.AsgnStmt = p.parseVarAssignStmt(p.l.NextToken().Content)
+		p.mark(stmt.AsgnStmt, ahead)
 	case token.TOKEN_SEP_SEMI:
 		break
 	default:
@@ -354,8 +371,9 @@ func (p *Parser) parseForStmt() (stmt *ast.ForStmt) {
 		stmt.Condition = parseExp(p)
 	}
 	p.NextTokenKind(token.TOKEN_SEP_SEMI) // ;
-	if !p.Expect(token.TOKEN_SEP_RPAREN) {
+	if ahead := p.l.LookAhead(); ahead.Kind != token.TOKEN_SEP_RPAREN {
 		stmt.ForTail = p.parseForTail()
+		p.mark(stmt.ForTail, ahead)
 	}
 	p.NextTokenKind(token.TOKEN_SEP_RPAREN) // )
 	p.ConsumeIf(token.TOKEN_SEP_SEMI)
@@ -378,9 +396,11 @@ func (p *Parser) parseForTail() *ast.VarAssignStmt {
 func (p *Parser) parseLoopStmt() (stmt *ast.LoopStmt) {
 	p.l.NextToken()
 	p.NextTokenKind(token.TOKEN_SEP_LPAREN)
-	p.NextTokenKind(token.TOKEN_KW_LET)
 
 	stmt = new(ast.LoopStmt)
+	if stmt.Const = p.ConsumeIf(token.TOKEN_KW_CONST); !stmt.Const {
+		p.NextTokenKind(token.TOKEN_KW_LET)
+	}
 	firstName := p.NextTokenKind(token.TOKEN_IDENTIFIER).Content
 	if p.ConsumeIf(token.TOKEN_SEP_COMMA) {
 		stmt.Key = firstName
@@ -16,18 +16,18 @@ func TestVarDeclStmt(t *testing.T) {
 		`let a,b,c = true,1+2,"good";`,
 	}
 	wants := []*VarDeclStmt{
-		{0, []string{"a"}, []Exp{&BinOpExp{BINOP_ADD, &StringLiteralExp{"a"}, &StringLiteralExp{"b"}}}},
-		{0, []string{"a"}, []Exp{&NilExp{}}},
-		{0, []string{"a", "b"}, []Exp{&NumberLiteralExp{int64(1)}, &NumberLiteralExp{int64(2)}}},
+		{0, []string{"a"}, []Exp{&BinOpExp{BINOP_ADD, &StringLiteralExp{"a"}, &StringLiteralExp{"b"}}}, false},
+		{0, []string{"a"}, []Exp{&NilExp{}}, false},
+		{0, []string{"a", "b"}, []Exp{&NumberLiteralExp{int64(1)}, &NumberLiteralExp{int64(2)}}, true},
 		{0, []string{"a", "b"}, []Exp{
 			&BinOpExp{BINOP_ADD, &NumberLiteralExp{int64(1)}, &NumberLiteralExp{int64(1)}},
 			&BinOpExp{BINOP_ADD, &NumberLiteralExp{int64(1)}, &NumberLiteralExp{int64(2)}},
-		}},
+		}, false},
 		{0, []string{"a", "b", "c"}, []Exp{
 			&TrueExp{},
 			&BinOpExp{BINOP_ADD, &NumberLiteralExp{int64(1)}, &NumberLiteralExp{int64(2)}},
 			&StringLiteralExp{"good"},
-		}},
+		}, false},
 	}
 	for i, src := range srcs {
 		l := newLexer(src)
@@ -320,17 +320,22 @@ loop(let k,v:arr){
 	print(k,v)
 }
 `,
+		`
+loop(const v:arr) print(v)`,
 	}
 	wants := []*LoopStmt{
 		{"", "v", parseExp(NewParser(newLexer("\nm[\"arr\"]"))), Block{
 			[]BlockStmt{NewParser(newLexer("\n\nprint(v)")).parseVarOpOrLabel()},
-		}},
+		}, false},
 		{"k", "v", parseExp(NewParser(newLexer("\narr"))), Block{
 			[]BlockStmt{NewParser(newLexer("\n\nprint(k,v)")).parseVarOpOrLabel()},
-		}},
+		}, false},
 		{"k", "v", parseExp(NewParser(newLexer("\narr"))), Block{
 			[]BlockStmt{NewParser(newLexer("\n\nprint(k,v)")).parseVarOpOrLabel()},
-		}},
+		}, false},
+		{"", "v", parseExp(NewParser(newLexer("\narr"))), Block{
+			[]BlockStmt{NewParser(newLexer("\nprint(v)")).parseVarOpOrLabel()},
+		}, true},
 	}
 	for i, src := range srcs {
 		l := newLexer(src)
@@ -488,11 +493,15 @@ class A{
 		#...
 	}
 }`,
+		`class B extends A{}`,
+		`class C extends fs.File {}`,
 	}
 	wants := []*ClassStmt{
-		{"A", nil, nil, nil},
+		{"A", nil, nil, nil, nil},
 		{"A", []string{"name", "show"}, []Exp{&StringLiteralExp{"jack"}, &FuncLiteralExp{FuncLiteral{6, nil, "", Block{}}}},
-			&FuncLiteralExp{FuncLiteral{3, nil, "", Block{}}}},
+			&FuncLiteralExp{FuncLiteral{3, nil, "", Block{}}}, nil},
+		{"B", nil, nil, nil, &NameExp{1, "A"}},
+		{"C", nil, nil, nil, &BinOpExp{BINOP_ATTR, &NameExp{1, "fs"}, &StringLiteralExp{"File"}}},
 	}
 	for i, src := range srcs {
 		l := newLexer(src)
@@ -512,10 +521,20 @@ func TestTryCatchStmt(t *testing.T) {
 catch(e)
 {}
 `,
+		`try{}finally{a++}`,
+		`try{}
+catch(e){}
+finally
+{
+	a++
+}`,
 	}
+	finally := []BlockStmt{&VarAssignStmt{0, ASIGN_OP_ADDEQ, []Var{{"a", nil}}, []Exp{&NumberLiteralExp{int64(1)}}}}
 	wants := []*TryCatchStmt{
-		{nil, "", 0, nil},
-		{nil, "e", 4, nil},
+		{nil, true, "", 0, nil, nil},
+		{nil, true, "e", 4, nil, nil},
+		{nil, false, "", 0, nil, finally},
+		{nil, true, "e", 2, nil, finally},
 	}
 	for i, src := range srcs {
 		l := newLexer(src)
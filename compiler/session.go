@@ -0,0 +1,102 @@
+package compiler
+
+import (
+	"gscript/compiler/ast"
+	"gscript/compiler/codegen"
+	"gscript/compiler/lexer"
+	"gscript/compiler/parser"
+	"gscript/proto"
+	"gscript/std"
+	"path"
+)
+
+// Session compiles code chunk by chunk into the main proto, e.g. for REPL. Declarations
+// of previous chunks are visible to later ones. Only standard libraries can be imported.
+type Session struct {
+	file string
+	gen  *codegen.Session
+}
+
+func NewSession(file string) *Session {
+	return &Session{file: file, gen: codegen.NewSession(file, nil)}
+}
+
+// Complie compiles code as a chunk, returns the main proto and address where codes of the
+// chunk start. If code is a single expression, its value is left in evaluation stack when
+// the chunk finishes. The session is kept unchanged if code is invalid, and errors are
+// returned as ast.ErrorList.
+func (s *Session) Complie(code []byte) (main proto.Proto, start uint32, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			errs, ok := r.(ast.ErrorList)
+			if !ok {
+				panic(r)
+			}
+			err = errs
+		}
+	}()
+	if p, exp, ok := s.parseExp(code); ok {
+		main, start = s.gen.GenExp(p, exp)
+		return
+	}
+
+	p := parser.NewParser(lexer.NewLexer(s.file, code))
+	prog := p.Parse()
+	if prog.Export.Exp != nil {
+		return main, 0, ast.ErrorList{{File: s.file, Msg: "export is not allowed here"}}
+	}
+	var imports []codegen.Import
+	for _, _import := range prog.Imports {
+		for _, lib := range _import.Libs {
+			if !lib.Stdlib {
+				return main, 0, ast.ErrorList{{File: s.file, Line: _import.Line, Msg: "only standard libraries can be imported here"}}
+			}
+			protoNumber, err := std.GetLibProtoNumByName(lib.Path)
+			if err != nil {
+				return main, 0, ast.ErrorList{{File: s.file, Line: _import.Line, Msg: err.Error()}}
+			}
+			alias := lib.Alias
+			if alias == "" {
+				alias = path.Base(lib.Path)
+			}
+			imports = append(imports, codegen.Import{
+				Line:        uint32(_import.Line),
+				ProtoNumber: protoNumber,
+				Alias:       alias,
+				StdLib:      true,
+			})
+		}
+	}
+	main, start = s.gen.GenChunk(p, prog, imports)
+	return
+}
+
+// try to parse code as a single expression
+func (s *Session) parseExp(code []byte) (p *parser.Parser, exp ast.Exp, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isErr := r.(ast.ErrorList); !isErr {
+				panic(r)
+			}
+			ok = false
+		}
+	}()
+	p = parser.NewParser(lexer.NewLexer(s.file, code))
+	return p, p.ParseExp(), true
+}
+
+// Rollback discards the last chunk, e.g. if it fails when running, and returns the main
+// proto without it.
+func (s *Session) Rollback() proto.Proto {
+	return s.gen.Rollback()
+}
+
+// Names returns names visible at top level
+func (s *Session) Names() []string {
+	return s.gen.Names()
+}
+
+// Variable returns index of the top level variable name in symbol table
+func (s *Session) Variable(name string) (idx uint32, ok bool) {
+	return s.gen.Variable(name)
+}
@@ -7,6 +7,11 @@ const (
 	TOKEN_NUMBER            // number
 	TOKEN_STRING            // string
 
+	// template string
+	TOKEN_TEMPLATE_HEAD   // `...${
+	TOKEN_TEMPLATE_MIDDLE // }...${
+	TOKEN_TEMPLATE_TAIL   // }...`
+
 	// seperator
 	TOKEN_SEP_DOT    // .
 	TOKEN_SEP_VARARG // ...
@@ -77,6 +82,7 @@ const (
 	TOKEN_KW_RETURN      // return
 	TOKEN_KW_FUNC        // func
 	TOKEN_KW_LET         // let
+	TOKEN_KW_CONST       // const
 	TOKEN_KW_TRUE        // true
 	TOKEN_KW_FALSE       // false
 	TOKEN_KW_NEW         // new
@@ -90,6 +96,9 @@ const (
 	TOKEN_KW_GOTO        // goto
 	TOKEN_KW_TRY         // try
 	TOKEN_KW_CATCH       // catch
+	TOKEN_KW_FINALLY     // finally
+	TOKEN_KW_EXTENDS     // extends
+	TOKEN_KW_INSTANCEOF  // instanceof
 )
 
 var TokenDescs = map[int]string{
@@ -154,6 +163,7 @@ var TokenDescs = map[int]string{
 	TOKEN_KW_RETURN:      "return",
 	TOKEN_KW_FUNC:        "func",
 	TOKEN_KW_LET:         "let",
+	TOKEN_KW_CONST:       "const",
 	TOKEN_KW_TRUE:        "true",
 	TOKEN_KW_FALSE:       "false",
 	TOKEN_KW_NEW:         "new",
@@ -167,9 +177,16 @@ var TokenDescs = map[int]string{
 	TOKEN_KW_GOTO:        "goto",
 	TOKEN_KW_TRY:         "try",
 	TOKEN_KW_CATCH:       "catch",
+	TOKEN_KW_FINALLY:     "finally",
+	TOKEN_KW_EXTENDS:     "extends",
+	TOKEN_KW_INSTANCEOF:  "instanceof",
+
+	TOKEN_TEMPLATE_HEAD:   "template string",
+	TOKEN_TEMPLATE_MIDDLE: "template string",
+	TOKEN_TEMPLATE_TAIL:   "template string",
 }
 
-var _eofToken = Token{Kind: TOKEN_EOF}
+var _eofToken = Token{Kind: TOKEN_EOF, Content: "EOF"}
 var EOFToken = &_eofToken
 
 type Token struct {
@@ -195,6 +212,7 @@ var Keywords = map[string]int{
 	"return":      TOKEN_KW_RETURN,
 	"func":        TOKEN_KW_FUNC,
 	"let":         TOKEN_KW_LET,
+	"const":       TOKEN_KW_CONST,
 	"true":        TOKEN_KW_TRUE,
 	"false":       TOKEN_KW_FALSE,
 	"new":         TOKEN_KW_NEW,
@@ -208,4 +226,7 @@ var Keywords = map[string]int{
 	"goto":        TOKEN_KW_GOTO,
 	"try":         TOKEN_KW_TRY,
 	"catch":       TOKEN_KW_CATCH,
+	"finally":     TOKEN_KW_FINALLY,
+	"extends":     TOKEN_KW_EXTENDS,
+	"instanceof":  TOKEN_KW_INSTANCEOF,
 }
@@ -0,0 +1,192 @@
+// Package engine embeds gscript into Go applications. Failures of scripts, including
+// syntax errors, runtime errors and calls of exit, are returned as errors and never
+// terminate the host process.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"gscript/compiler"
+	"gscript/compiler/ast"
+	"gscript/proto"
+	"gscript/std"
+	"gscript/vm"
+)
+
+// CompileError reports a syntax or semantic error with its source position
+type CompileError = ast.Error
+
+// CompileErrors is returned if compiling fails, it contains all errors found in the script
+// and files imported by it. errors.As finds the first *CompileError in it.
+type CompileErrors = ast.ErrorList
+
+// RuntimeError reports an error occurred when executing script with its source position
+type RuntimeError = vm.RuntimeError
+
+// ExitError is returned if script calls exit
+type ExitError = vm.ExitError
+
+// Sandbox restricts capabilities of script, see SetSandbox
+type Sandbox = vm.Sandbox
+
+// Limits restricts resources used by script, zero value of a field means unlimited
+type Limits = vm.Limits
+
+// a *RuntimeError wraps one of them if a limit is exceeded, use errors.Is to check
+var (
+	ErrInstructionLimit = vm.ErrInstructionLimit
+	ErrCallDepthLimit   = vm.ErrCallDepthLimit
+	ErrStackLimit       = vm.ErrStackLimit
+)
+
+// Program is a compiled script together with the files it imports
+type Program struct {
+	protos []proto.Proto
+	host   *Host // nil if compiled without Host
+}
+
+// Compile compiles script code. filename is used in error messages and to resolve
+// relative imports.
+func Compile(filename, code string) (*Program, error) {
+	protos, err := compiler.ComplieWithSrcCode([]byte(code), filename)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{protos: protos}, nil
+}
+
+// CompileFile compiles script file at path
+func CompileFile(path string) (*Program, error) {
+	protos, err := compiler.ComplieWithSrcFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{protos: protos}, nil
+}
+
+// upvalues of functions are bound and infos of functions are filled by VM at runtime, every
+// VM needs its own copy of protos
+func (p *Program) copyProtos() []proto.Proto {
+	protos := make([]proto.Proto, len(p.protos))
+	for i, _proto := range p.protos {
+		funcs := make([]proto.FuncProto, len(_proto.Funcs))
+		for j, f := range _proto.Funcs {
+			f.UpValueTable = nil
+			f.Info = copyInfo(f.Info)
+			funcs[j] = f
+		}
+		anonymousFuncs := make([]proto.AnonymousFuncProto, len(_proto.AnonymousFuncs))
+		for j, f := range _proto.AnonymousFuncs {
+			f.Info = copyInfo(f.Info)
+			anonymousFuncs[j] = f
+		}
+		_proto.Funcs, _proto.AnonymousFuncs = funcs, anonymousFuncs
+		protos[i] = _proto
+	}
+	return protos
+}
+
+func copyInfo(info *proto.BasicInfo) *proto.BasicInfo {
+	clone := *info
+	return &clone
+}
+
+// VM executes a Program. A VM is not safe for concurrent use, but several VMs can
+// execute the same Program concurrently.
+type VM struct {
+	vm *vm.VM
+}
+
+// NewVM creates a VM to execute prog
+func NewVM(prog *Program) (*VM, error) {
+	stdlibs, err := std.ReadProtos()
+	if err != nil {
+		return nil, err
+	}
+	v := vm.NewVM(prog.copyProtos(), stdlibs)
+	if prog.host != nil {
+		v.SetHostValues(append([]interface{}(nil), prog.host.values...))
+	}
+	return &VM{vm: v}, nil
+}
+
+// SetLimits sets limits of the following Run and Call. MaxInstructions applies to each
+// Run or Call separately.
+func (v *VM) SetLimits(limits Limits) {
+	v.vm.SetLimits(limits)
+}
+
+// SetSandbox restricts capabilities of script. Builtin functions throw an Error of kind
+// Permission if script oversteps, nil means unrestricted. Functions provided by Host are
+// not restricted.
+func (v *VM) SetSandbox(sandbox *Sandbox) {
+	v.vm.SetSandbox(sandbox)
+}
+
+// Run executes top level code of the script
+func (v *VM) Run() error {
+	return v.RunContext(context.Background())
+}
+
+// RunContext is like Run, but script stops with a *RuntimeError wrapping ctx.Err() once
+// ctx is done. Blocking builtin functions such as reading a file are not interrupted, except
+// time.sleep and waiting for processes.
+func (v *VM) RunContext(ctx context.Context) error {
+	v.vm.SetContext(ctx)
+	return v.vm.Run()
+}
+
+// Call calls the global function name with args after Run succeeds, and returns all
+// return values of the function. See ToValue and FromValue for how values are converted.
+func (v *VM) Call(name string, args ...interface{}) ([]interface{}, error) {
+	return v.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is like Call, but the function stops once ctx is done as RunContext does
+func (v *VM) CallContext(ctx context.Context, name string, args ...interface{}) ([]interface{}, error) {
+	v.vm.SetContext(ctx)
+	return v.call(args, func(vals []interface{}) ([]interface{}, error) {
+		return v.vm.Call(name, vals...)
+	})
+}
+
+// CallValue calls function fn of script with args, and returns all return values of it. fn is
+// usually got from arguments of a Func or return values of Call, such as a callback or an event
+// handler. CallValue can be called after Run succeeds, or by a Func while script is running.
+//
+// Exception not caught by fn is returned as *RuntimeError whose Uncaught is true, a Func can
+// return the error to rethrow the exception to script.
+func (v *VM) CallValue(fn interface{}, args ...interface{}) ([]interface{}, error) {
+	return v.CallValueContext(context.Background(), fn, args...)
+}
+
+// CallValueContext is like CallValue, but fn stops once ctx is done as RunContext does. ctx is
+// ignored if it is called by a Func while script is running, fn runs with the context of script.
+func (v *VM) CallValueContext(ctx context.Context, fn interface{}, args ...interface{}) ([]interface{}, error) {
+	if !v.vm.Running() {
+		v.vm.SetContext(ctx)
+	}
+	return v.call(args, func(vals []interface{}) ([]interface{}, error) {
+		return v.vm.CallValue(fn, vals...)
+	})
+}
+
+// convert args to script values for call, and convert its return values to Go values
+func (v *VM) call(args []interface{}, call func(vals []interface{}) ([]interface{}, error)) ([]interface{}, error) {
+	vals := make([]interface{}, len(args))
+	for i, arg := range args {
+		val, err := ToValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %v", i+1, err)
+		}
+		vals[i] = val
+	}
+	rets, err := call(vals)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rets {
+		rets[i] = FromValue(rets[i])
+	}
+	return rets, nil
+}
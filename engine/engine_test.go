@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"gscript/proto"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newVM(t *testing.T, code string) *VM {
+	prog, err := Compile("test.gs", code)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	v, err := NewVM(prog)
+	if err != nil {
+		t.Fatalf("create vm failed: %v", err)
+	}
+	return v
+}
+
+func TestCall(t *testing.T) {
+	v := newVM(t, `
+let base = 10
+func add(a, b) {
+	return a + b + base
+}
+func split(arr) {
+	return arr[0], {name: arr[1]}
+}
+base = 100
+`)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	rets, err := v.Call("add", 1, 2.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rets, []interface{}{103.5}) {
+		t.Fatalf("add returns %v", rets)
+	}
+	rets, err = v.Call("split", []interface{}{1, "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{int64(1), map[interface{}]interface{}{"name": "foo"}}
+	if !reflect.DeepEqual(rets, want) {
+		t.Fatalf("split returns %v, want %v", rets, want)
+	}
+	if _, err = v.Call("undefined"); err == nil {
+		t.Fatal("calling undefined function should fail")
+	}
+}
+
+// VMs of the same Program share nothing, run with -race to check it
+func TestConcurrentVMs(t *testing.T) {
+	prog, err := Compile("test.gs", `
+class Counter {
+	__self(n) { this.n = n }
+	add(d) { this.n += d }
+}
+func sum(arr) {
+	let c = new Counter(0)
+	arr.map(func(v) { c.add(v) })
+	return c.n
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := NewVM(prog)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err = v.Run(); err != nil {
+				t.Error(err)
+				return
+			}
+			rets, err := v.Call("sum", []interface{}{i, 1})
+			if err != nil || !reflect.DeepEqual(rets, []interface{}{int64(i + 1)}) {
+				t.Errorf("sum returns %v, %v", rets, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := Compile("test.gs", "let a = 1\nlet b = (a + 1\n")
+	var e *CompileError
+	if !errors.As(err, &e) {
+		t.Fatalf("want CompileError, but got %v", err)
+	}
+	// at the end of line instead of the next line
+	if !strings.HasSuffix(e.File, "test.gs") || e.Line != 2 || e.Column != 15 {
+		t.Fatalf("wrong position of error: %v", e)
+	}
+	_, err = Compile("test.gs", "let a = 1 +\n")
+	if !errors.As(err, &e) || e.Line != 1 || e.Column != 12 || !strings.Contains(e.Msg, "'EOF'") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = Compile("test.gs", "let a = 1\nprint(b)\n")
+	if !errors.As(err, &e) || e.Line != 2 || e.Column != 7 || !strings.Contains(e.Msg, "undeclared name 'b'") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// statements whose names are undeclared
+	_, err = Compile("test.gs", "let a = 1\nnope(a)\n\tnope.f()\n\tnope++\n")
+	errs, ok := err.(CompileErrors)
+	if !ok || len(errs) != 3 || errs[0].Line != 2 || errs[1].Line != 3 || errs[2].Line != 4 {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+
+	// all errors are reported
+	_, err = Compile("test.gs", "print(b)\nlet a = 1 +\nprint(c)\n")
+	errs, ok = err.(CompileErrors)
+	if !ok || len(errs) != 2 || errs[0].Line != 1 || errs[1].Line != 3 {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+
+	_, err = Compile("test.gs", "let y = 0\n\tconst x\n")
+	if !errors.As(err, &e) || e.Line != 2 || e.Column != 2 || !strings.Contains(e.Msg, "constant 'x'") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// constants can not be assigned, even in closures
+	_, err = Compile("test.gs", "const a = 1\nfunc f() {\n\ta += 1\n}\nenum {B}\nB++\n")
+	errs, ok = err.(CompileErrors)
+	if !ok || len(errs) != 2 || errs[0].Line != 3 || errs[0].Column != 2 || !strings.Contains(errs[0].Msg, "constant 'a'") ||
+		errs[1].Line != 6 || !strings.Contains(errs[1].Msg, "enum constant 'B'") {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+
+	// super(...) is only allowed as the first statement of __self
+	_, err = Compile("test.gs", "class A {}\nclass B extends A {\n\t__self() {\n\t\tthis.a = 1\n\t\tsuper()\n\t}\n}\n")
+	if !errors.As(err, &e) || e.Line != 5 || !strings.Contains(e.Msg, "super(...)") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	v := newVM(t, `
+func divide(a, b) {
+	if (b == 0) {
+		throw("divided by zero")
+	}
+	return a / b
+}
+func index(arr) {
+	return arr[10]
+}
+`)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := v.Call("divide", 1, 0)
+	var e *RuntimeError
+	if !errors.As(err, &e) || !e.Uncaught || e.Exception != "divided by zero" || e.Line != 4 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// VM is still usable after a failed call
+	rets, err := v.Call("divide", 6, 3)
+	if err != nil || !reflect.DeepEqual(rets, []interface{}{int64(2)}) {
+		t.Fatalf("divide returns %v, %v", rets, err)
+	}
+	_, err = v.Call("index", []interface{}{})
+	if !errors.As(err, &e) || e.Uncaught || e.Line != 9 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// line tables are kept in compiled protos, so runtime errors of loaded protos have positions
+func TestProtoRoundTrip(t *testing.T) {
+	prog, err := Compile("test.gs", `
+func index(arr) {
+	return arr[10]
+}
+let a = [1]
+  index(a)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = proto.WriteProtos(&buf, prog.protos); err != nil {
+		t.Fatal(err)
+	}
+	_, protos, err := proto.ReadProtos(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVM(&Program{protos: protos})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Run()
+	var e *RuntimeError
+	if !errors.As(err, &e) || !strings.HasSuffix(e.File, "test.gs") || e.Line != 3 || e.Column != 12 ||
+		!strings.Contains(e.Traceback, `test.gs", line 6, in <module>`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExit(t *testing.T) {
+	v := newVM(t, `
+import os
+os.exit(3)
+print("unreachable")
+`)
+	var e *ExitError
+	if err := v.Run(); !errors.As(err, &e) || e.Code != 3 {
+		t.Fatalf("want ExitError with code 3, but got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"fmt"
+	"gscript/compiler"
+	"gscript/std"
+	"gscript/vm"
+	"gscript/vm/types"
+)
+
+// Func is a Go function callable from script. Its return values are converted by ToValue,
+// and the error it returns is thrown as an Error object in script.
+type Func func(args Args) ([]interface{}, error)
+
+// Host holds Go functions and values exposed to scripts, either as globals or as modules
+// imported by `import name`. Globals override builtin functions of the same name.
+//
+// Scripts compiled by a Host refer to its globals and modules when running, so they
+// should be registered before compiling.
+type Host struct {
+	values []interface{}
+	names  compiler.Host
+}
+
+func NewHost() *Host {
+	return &Host{
+		names: compiler.Host{
+			Globals: make(map[string]uint32),
+			Modules: make(map[string]uint32),
+		},
+	}
+}
+
+// SetGlobal exposes v as global name. v can be a Func or any value accepted by ToValue.
+func (h *Host) SetGlobal(name string, v interface{}) error {
+	val, err := toHostValue(name, v)
+	if err != nil {
+		return err
+	}
+	h.set(h.names.Globals, name, val)
+	return nil
+}
+
+// SetModule exposes members as module name, scripts get an Object of members by
+// `import name`. Values of members can be Func or any value accepted by ToValue.
+func (h *Host) SetModule(name string, members map[string]interface{}) error {
+	if _, err := std.GetLibProtoNumByName(name); err == nil {
+		return fmt.Errorf("module '%s' conflicts with standard library", name)
+	}
+	module := types.NewObjectN(len(members))
+	for k, v := range members {
+		val, err := toHostValue(name+"."+k, v)
+		if err != nil {
+			return err
+		}
+		module.Set(k, val)
+	}
+	h.set(h.names.Modules, name, module)
+	return nil
+}
+
+// reuse the index of name if it has been set, programs compiled before refer to it
+func (h *Host) set(names map[string]uint32, name string, val interface{}) {
+	if idx, ok := names[name]; ok {
+		h.values[idx] = val
+		return
+	}
+	names[name] = uint32(len(h.values))
+	h.values = append(h.values, val)
+}
+
+// Compile is like Compile of package, but code can refer to globals and modules of h
+func (h *Host) Compile(filename, code string) (*Program, error) {
+	protos, err := compiler.ComplieWithHost([]byte(code), filename, &h.names)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{protos: protos, host: h}, nil
+}
+
+// CompileFile is like CompileFile of package, but script can refer to globals and modules of h
+func (h *Host) CompileFile(path string) (*Program, error) {
+	protos, err := compiler.ComplieWithHostFile(path, &h.names)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{protos: protos, host: h}, nil
+}
+
+func toHostValue(name string, v interface{}) (interface{}, error) {
+	switch fn := v.(type) {
+	case Func:
+		return newHostFunc(name, fn), nil
+	case func(Args) ([]interface{}, error):
+		return newHostFunc(name, fn), nil
+	}
+	val, err := ToValue(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return val, nil
+}
+
+func newHostFunc(name string, fn Func) interface{} {
+	return vm.NewHostFunc(name, func(args []interface{}) ([]interface{}, error) {
+		rets, err := fn(args)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rets {
+			if rets[i], err = ToValue(rets[i]); err != nil {
+				return nil, fmt.Errorf("return value %d of %s: %v", i+1, name, err)
+			}
+		}
+		return rets, nil
+	})
+}
+
+// Args are arguments passed to a Func
+type Args []interface{}
+
+func (a Args) get(i int, want string) (interface{}, error) {
+	if i >= len(a) {
+		return nil, fmt.Errorf("missing argument %d, want %s", i+1, want)
+	}
+	return a[i], nil
+}
+
+func (a Args) typeError(i int, want string) error {
+	return fmt.Errorf("argument %d should be %s, but got %s", i+1, want, vm.TypeOf(a[i]))
+}
+
+// Int returns the ith argument as an Integer
+func (a Args) Int(i int) (int64, error) {
+	v, err := a.get(i, "Integer")
+	if err != nil {
+		return 0, err
+	}
+	if num, ok := v.(int64); ok {
+		return num, nil
+	}
+	return 0, a.typeError(i, "Integer")
+}
+
+// Float returns the ith argument as a Number, Integer is converted to float64
+func (a Args) Float(i int) (float64, error) {
+	v, err := a.get(i, "Number")
+	if err != nil {
+		return 0, err
+	}
+	switch num := v.(type) {
+	case float64:
+		return num, nil
+	case int64:
+		return float64(num), nil
+	}
+	return 0, a.typeError(i, "Number")
+}
+
+// String returns the ith argument as a String
+func (a Args) String(i int) (string, error) {
+	v, err := a.get(i, "String")
+	if err != nil {
+		return "", err
+	}
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+	return "", a.typeError(i, "String")
+}
+
+// Bool returns the ith argument as a Boolean
+func (a Args) Bool(i int) (bool, error) {
+	v, err := a.get(i, "Boolean")
+	if err != nil {
+		return false, err
+	}
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, a.typeError(i, "Boolean")
+}
+
+// Array returns the ith argument as an Array
+func (a Args) Array(i int) (*types.Array, error) {
+	v, err := a.get(i, "Array")
+	if err != nil {
+		return nil, err
+	}
+	if arr, ok := v.(*types.Array); ok {
+		return arr, nil
+	}
+	return nil, a.typeError(i, "Array")
+}
+
+// Object returns the ith argument as an Object
+func (a Args) Object(i int) (*types.Object, error) {
+	v, err := a.get(i, "Object")
+	if err != nil {
+		return nil, err
+	}
+	if obj, ok := v.(*types.Object); ok {
+		return obj, nil
+	}
+	return nil, a.typeError(i, "Object")
+}
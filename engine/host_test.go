@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	var logs []string
+	host := NewHost()
+	err := host.SetGlobal("print", Func(func(args Args) ([]interface{}, error) {
+		logs = append(logs, strings.TrimSpace(fmt.Sprintln(args...)))
+		return nil, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = host.SetGlobal("version", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = host.SetModule("calc", map[string]interface{}{
+		"pi": 3.14,
+		"div": func(args Args) ([]interface{}, error) {
+			a, err := args.Int(0)
+			if err != nil {
+				return nil, err
+			}
+			b, err := args.Int(1)
+			if err != nil {
+				return nil, err
+			}
+			if b == 0 {
+				return nil, errors.New("divided by zero")
+			}
+			return []interface{}{a / b, a % b}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = host.SetModule("fs", nil); err == nil {
+		t.Fatal("module conflicting with standard library should be rejected")
+	}
+
+	prog, err := host.Compile("test.gs", `
+import calc as c
+print(version, c.pi)
+let q, r = c.div(7, 2)
+print(q, r)
+try {
+	c.div(1, 0)
+} catch (e) {
+	print(e.message)
+}
+try {
+	c.div("1")
+} catch (e) {
+	print(e.message)
+}
+func div(a, b) {
+	let q, r = c.div(a, b)
+	return q, r
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVM(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1.0 3.14", "3 1", "divided by zero", "argument 1 should be Integer, but got String"}
+	if !reflect.DeepEqual(logs, want) {
+		t.Fatalf("got logs %q, want %q", logs, want)
+	}
+	rets, err := v.Call("div", 9, 4)
+	if err != nil || !reflect.DeepEqual(rets, []interface{}{int64(2), int64(1)}) {
+		t.Fatalf("div returns %v, %v", rets, err)
+	}
+
+	// names of host are unknown to programs compiled without it
+	if _, err = Compile("test.gs", "import calc"); err == nil {
+		t.Fatal("importing host module without host should fail")
+	}
+}
+
+func TestCallValue(t *testing.T) {
+	var v *VM
+	host := NewHost()
+	// each calls fn with elements of arr, exceptions thrown by fn are rethrown to script
+	err := host.SetGlobal("each", Func(func(args Args) ([]interface{}, error) {
+		arr, err := args.Array(0)
+		if err != nil {
+			return nil, err
+		}
+		for _, elem := range arr.Data {
+			if _, err = v.CallValue(args[1], elem); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := host.Compile("test.gs", `
+func test() {
+	let sum = 0
+	each([1, 2, 3], func(v) { sum += v })
+	try {
+		each([4, 5], func(v) { throw("stop at " + v) })
+	} catch (e) {
+		return sum, e
+	}
+}
+func handler() {
+	return func(x) {
+		if (x < 0) throw("negative")
+		return x * 2
+	}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err = NewVM(prog); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	rets, err := v.Call("test")
+	if err != nil || !reflect.DeepEqual(rets, []interface{}{int64(6), "stop at 4"}) {
+		t.Fatalf("test returns %v, %v", rets, err)
+	}
+	rets, err = v.Call("handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := rets[0]
+	rets, err = v.CallValue(handler, 21)
+	if err != nil || !reflect.DeepEqual(rets, []interface{}{int64(42)}) {
+		t.Fatalf("handler returns %v, %v", rets, err)
+	}
+	_, err = v.CallValue(handler, -1)
+	var e *RuntimeError
+	if !errors.As(err, &e) || !e.Uncaught || e.Exception != "negative" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = v.CallValue("handler"); err == nil {
+		t.Fatal("calling a String should fail")
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	v := newVM(t, `
+func spin() {
+	while (true) {}
+}
+func recurse(n) {
+	return recurse(n + 1)
+}
+func sum(n) {
+	return n + sum(n - 1)
+}
+func recurseMap(n) {
+	return [n].map(func(v) { return recurseMap(v + 1) })
+}
+`)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	v.SetLimits(Limits{MaxInstructions: 10000})
+	if _, err := v.Call("spin"); !errors.Is(err, ErrInstructionLimit) {
+		t.Fatalf("want ErrInstructionLimit, but got %v", err)
+	}
+
+	v.SetLimits(Limits{MaxCallDepth: 100})
+	_, err := v.Call("recurse", 0)
+	var e *RuntimeError
+	if !errors.Is(err, ErrCallDepthLimit) || !errors.As(err, &e) || e.Line != 6 {
+		t.Fatalf("want ErrCallDepthLimit at line 6, but got %v", err)
+	}
+
+	// every call of sum keeps its n in stack
+	v.SetLimits(Limits{MaxStackSize: 100})
+	if _, err = v.Call("sum", 10); !errors.Is(err, ErrStackLimit) {
+		t.Fatalf("want ErrStackLimit, but got %v", err)
+	}
+
+	// recursion through callbacks runs on Go stack, it is limited without Limits
+	v.SetLimits(Limits{})
+	if _, err = v.Call("recurseMap", 0); !errors.Is(err, ErrCallDepthLimit) || !errors.As(err, &e) || e.Line != 12 {
+		t.Fatalf("want ErrCallDepthLimit at line 12, but got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := v.CallContext(ctx, "spin"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, but got %v", err)
+	}
+}
+
+func TestSleepInterrupted(t *testing.T) {
+	v := newVM(t, `
+import time
+time.sleep(10000)
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := v.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, but got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("sleep is not interrupted")
+	}
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := newVM(t, `
+import fs
+import os
+func check(f) {
+	try {
+		f()
+		return "ok"
+	} catch (e) {
+		return e.kind
+	}
+}
+func run(dir) {
+	return [
+		check(func() { fs.readFile(dir + "/a.txt") }),
+		check(func() { fs.create(dir + "/b.txt").close() }),
+		check(func() { fs.remove(dir + "/a.txt") }),
+		check(func() { os.exec("true") }),
+		check(func() { os.spawn("true") }),
+		check(func() { os.setEnv("GSCRIPT_SANDBOX_TEST", "1") }),
+		check(func() { fs.glob(dir + "/*.txt") }),
+		check(func() { fs.glob("/*") }),
+		check(func() { fs.walk("/", func() {}) }),
+	]
+}
+func walk(dir) {
+	let names = []
+	fs.walk(dir, func(path, stat) { append(names, stat.name) })
+	return [fs.glob(dir + "/*.txt").len(), names.sub(1)]
+}
+`)
+	v.SetSandbox(&Sandbox{ReadRoots: []string{dir}})
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	rets, err := v.Call("run", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"ok", "Permission", "Permission", "Permission", "Permission", "Permission", "ok", "Permission", "Permission"}
+	if !reflect.DeepEqual(rets[0], want) {
+		t.Fatalf("got %v, want %v", rets[0], want)
+	}
+	if rets, err = v.Call("walk", dir); err != nil {
+		t.Fatal(err)
+	}
+	want = []interface{}{int64(1), []interface{}{"a.txt"}}
+	if !reflect.DeepEqual(rets[0], want) {
+		t.Fatalf("got %v, want %v", rets[0], want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+}
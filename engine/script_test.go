@@ -0,0 +1,392 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// each script defines function test, whose return value is checked
+var scriptTests = []struct {
+	name string
+	code string
+	want interface{}
+}{
+	{"loop array", `
+func test() {
+	let res = []
+	loop (let i, v : [10, 20]) append(res, i, v)
+	loop (let v : [30]) append(res, v)
+	return res
+}`, []interface{}{int64(0), int64(10), int64(1), int64(20), int64(30)}},
+	{"loop object", `
+func test() {
+	let obj, sum = {a: 1, b: 2}, 0
+	loop (let k, v : obj) {
+		obj[k + k] = v
+		sum += v
+	}
+	return sum
+}`, int64(3)},
+	{"delete keeps order", `
+func test() {
+	let obj, res = {}, []
+	for (let i = 0; i < 12; i++) obj[i] = i
+	for (let i = 0; i < 12; i += 3) delete(obj, i)
+	obj[0] = "new"
+	delete(obj, 100)
+	loop (let k, v : obj) append(res, k)
+	append(res, len(obj))
+	return res
+}`, []interface{}{int64(1), int64(2), int64(4), int64(5), int64(7), int64(8), int64(10), int64(11),
+		int64(0), int64(9)}},
+	{"loop string", `
+func test() {
+	let res = []
+	loop (let i, c : "aé") append(res, i, c)
+	return res
+}`, []interface{}{int64(0), int64('a'), int64(1), int64('é')}},
+	{"loop buffer", `
+import Buffer
+func test() {
+	let res = []
+	loop (let i, b : Buffer.from("hé")) append(res, i, b)
+	loop (let b : Buffer.alloc(1)) append(res, b)
+	return res
+}`, []interface{}{int64(0), int64('h'), int64(1), int64(0xc3), int64(2), int64(0xa9), int64(0)}},
+	{"string index", `
+func test() {
+	let a, b = "aé", "xyz"
+	let res = []
+	for (let i = 0; i < len(a) + len(b); i++) {
+		append(res, i < len(a) ? a[i] : b[i - len(a)], a[1])
+	}
+	return res
+}`, []interface{}{int64('a'), int64('é'), int64('é'), int64('é'), int64('x'), int64('é'),
+		int64('y'), int64('é'), int64('z'), int64('é')}},
+	{"loop break continue", `
+func test() {
+	let sum = 0
+	loop (let v : [1, 2, 3, 4, 5, 6]) {
+		switch (v) {
+		case 2:
+			continue
+		case 4:
+			break
+		}
+		if (v == 5) break
+		sum += v
+	}
+	return sum
+}`, int64(8)},
+	{"loop closure", `
+func test() {
+	let fns, res = [], []
+	loop (let v : [1, 2]) append(fns, func() { return v })
+	loop (let f : fns) append(res, f())
+	return res
+}`, []interface{}{int64(1), int64(2)}},
+	{"loop iterator protocol", `
+class Range {
+	__self(n) { this.n = n }
+	__iter() {
+		let i, n = 0, this.n
+		return {next: func() {
+			if (i >= n) return {done: true}
+			return {value: i * 10, key: i++ == 0 ? "first" : nil}
+		}}
+	}
+}
+func test() {
+	let res = []
+	loop (let k, v : new Range(3)) append(res, k, v)
+	return res
+}`, []interface{}{"first", int64(0), int64(1), int64(10), int64(2), int64(20)}},
+	{"string utf8", `
+import Buffer
+func test() {
+	let s, 名字 = "h\xe9\u{4f60}!", "\u{1F600}"
+	let res = [len(s), s[2], sub(s, 1, 3), chars(s), codePoints(名字)[0], Buffer.from(s).cap()]
+	loop (let i, c : s) if (c == 0x4f60) append(res, i)
+	return res
+}`, []interface{}{int64(4), int64(0x4f60), "é你", []interface{}{"h", "é", "你", "!"}, int64(0x1F600),
+		int64(7), int64(2)}},
+	{"template string", "" +
+		"func test() {\n" +
+		"	let name, arr = \"gs\", [1]\n" +
+		"	return `${name}: ${len(arr) + 1} ${arr}\n${`<${nil}>`} \\${x}` + r\"\\n\"\n" +
+		"}", "gs: 2 Array[1]\n<<nil>> ${x}\\n"},
+	{"strings", `
+import strings
+func test() {
+	let s = strings.trim("  a,b,,c\n")
+	let parts = strings.split(s, ",")
+	let res = [strings.join(parts, "|"), strings.index("héllo", "l"), strings.padStart("7", 3, "0"),
+		strings.replace(s, ",", ";", 1), strings.parseInt("-ff", 16), strings.toFixed(1.005, 1),
+		strings.hasPrefix(s, "a,"), strings.upper("é")]
+	try { strings.repeat("a", -1) } catch (e) { append(res, e.kind) }
+	try { strings.parseInt("1", 37) } catch (e) { append(res, e.kind) }
+	try { strings.toFixed(1, -1) } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{"a|b||c", int64(2), "007", "a;b,,c", int64(-255), "1.0", true, "É",
+		"Invalid", "Invalid", "Invalid"}},
+	{"primitive methods", `
+func test() {
+	let arr = "b, c,a".split(",")
+	arr.push(" d")
+	loop (let i, v : arr) arr[i] = v.trim()
+	let last = arr.sort().pop()
+	let res = [arr.join("-"), last, "héllo".len(), "x".padStart(3, "0"), (255).toString(16),
+		1.5.toFixed(2), [3, 1.5, 2].sort(), "abc".upper().sub(1)]
+	try { let s = (255).toString(1) } catch (e) { append(res, e.kind) }
+	try { let s = 1.5.toString(2) } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{"a-b-c", "d", int64(5), "00x", "ff", "1.50", []interface{}{1.5, int64(2), int64(3)}, "BC",
+		"Invalid", "Invalid"}},
+	{"arrays", `
+import arrays
+func test() {
+	let arr = arrays.sort([3, 1, 2], func(a, b) { return a > b })
+	let sum = arr.map(func(v, i) { return v * i }).reduce(func(acc, v) { return acc + v }, 0)
+	let odd = arr.filter(func(v) { return v % 2 == 1 })
+	arr.insert(1, 5)
+	let res = [sum, odd, arr.find(func(v) { return v > 3 }), arr.indexOf(1), arr.splice(0, 2, 0),
+		arrays.concat(arr, [4], 6), [[1, [2]], 3].flat(2), [1, 2].reverse(), arr.removeAt(1)]
+	try { arr.insert(-1, 0) } catch (e) { append(res, e.kind) }
+	try { arr.removeAt(len(arr)) } catch (e) { append(res, e.kind) }
+	try { arr.splice(0, -1) } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{int64(4), []interface{}{int64(3), int64(1)}, int64(5), int64(3),
+		[]interface{}{int64(3), int64(5)}, []interface{}{int64(0), int64(2), int64(1), int64(4), int64(6)},
+		[]interface{}{int64(1), int64(2), int64(3)}, []interface{}{int64(2), int64(1)}, int64(2),
+		"Invalid", "Invalid", "Invalid"}},
+	{"callback exceptions", `
+import strings
+func rec(n) {
+	if (n == 0) throw("bottom")
+	return [n].map(func(v) { return rec(v - 1) })
+}
+func test() {
+	let res, arr = [], [3, 1, 2]
+	try {
+		arr = arr.sort(func(a, b) { throw("cmp") })
+	} catch (e) { append(res, e) }
+	try {
+		arr = ["x"].filter(func(s) { return strings.parseInt(s) })
+	} catch (e) { append(res, e.kind) }
+	try { rec(3) } catch (e) { append(res, e) }
+	append(res, [1, 2].map(func(v) {
+		try { throw(v) } catch (e) { return e * 10 }
+	}))
+	return res
+}`, []interface{}{"cmp", "Invalid", "bottom", []interface{}{int64(10), int64(20)}}},
+	{"json", `
+import json
+import Buffer
+func test() {
+	let v = json.parse("{\"b\": [1, 2.5, null], \"a\": {\"s\": \"\\u00e9\"}, \"f\": 1.0}")
+	v.fn = func() {}
+	let res = [json.stringify(v), json.stringify([Buffer.from("hi"), {1: true}], "")]
+	try { json.parse("[1,") } catch (e) { append(res, e.kind) }
+	v.b[2] = v
+	try { json.stringify(v) } catch (e) { append(res, e.kind) }
+	try { json.stringify(1, 11) } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{`{"b":[1,2.5,null],"a":{"s":"é"},"f":1.0}`, `["aGk=",{"1":true}]`, "Invalid", "Invalid", "Invalid"}},
+	{"math", `
+import math
+func test() {
+	let res = [math.floor(-2.5), math.round(2.5), math.pow(3, 4), math.pow(4, 0.5), math.abs(-7),
+		math.toInt(-1.9), math.toFloat(2), math.min(2, 1.5), math.isInf(-math.Inf, -1), math.isNaN(math.NaN)]
+	try { math.addExact(math.MAX_INT, 1) } catch (e) { append(res, e.kind) }
+	try { math.toInt(math.NaN) } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{int64(-3), int64(3), int64(81), 2.0, int64(7), int64(-1), 2.0, 1.5, true, true,
+		"Overflow", "Invalid"}},
+	{"time", `
+import time
+func test() {
+	let t = time.date(2024, 2, 29, 23, 30, 0, 0, "UTC")
+	let sh = t.in("Asia/Shanghai")
+	let p = time.parse(time.DATETIME, "2024-03-01 08:00:00", "Asia/Shanghai")
+	let res = [t.format(), sh.format(time.DATETIME), sh.day, t.weekday, p.sub(t) / time.MINUTE, sh.equal(t),
+		t.add(time.HOUR).format(time.DATE), time.formatDuration(time.parseDuration("1m30s")), time.unix(0).utc().year]
+	try { time.parse(time.DATE, "2024-02-30") } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{"2024-02-29T23:30:00Z", "2024-03-01 07:30:00", int64(1), int64(4), int64(30), true, "2024-03-01",
+		"1m30s", int64(1970), "Invalid"}},
+	{"regexp", `
+import regexp
+func test() {
+	let re = regexp.compile(r"(?P<key>\w+)=(?P<val>\d+)?")
+	let s = "é a=1 b= c=3"
+	let res = [type(re), re.test(s), re.find(s), re.findIndex(s), re.match(s), re.groups("x=").val,
+		re.findAll(s, 2), re.replace(s, "${val}:$key"), re.replace(s, func(m, k, v) { return v == nil ? 0 : v + v }),
+		regexp.split(regexp.compile(r"\s+"), " a  b "), regexp.quote("1+1")]
+	try { regexp.compile("[") } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{"Regexp", true, "a=1", []interface{}{int64(2), int64(5)}, []interface{}{"a=1", "a", "1"}, nil,
+		[]interface{}{"a=1", "b="}, "é 1:a :b 3:c", "é 11 0 33", []interface{}{"", "a", "b", ""}, `1\+1`, "Invalid"}},
+	{"path", `
+import path
+func ext(name) {
+	if (path.match("*.tar.*", name)) return ".tar" + path.ext(name)
+}
+func test() {
+	return [path.join("a", "b/../c", "d.gs"), path.dir("/x/y.gs"), path.base("/x/y/"), path.ext("y.gs"),
+		path.split("x/y.gs"), path.rel("/a/b", "/a/c"), path.isAbs("a"), path.clean("a//b/./"), ext("x.tar.gz"), ext("x")]
+}`, []interface{}{"a/c/d.gs", "/x", "y", ".gs", []interface{}{"x/", "y.gs"}, "../c", false, "a/b", ".tar.gz", nil}},
+	{"spawn", `
+import os
+func test() {
+	let p = os.spawn("sh", ["-c", "echo $A; cat >&2; exit 3"], {env: {A: "a"}, input: "in"})
+	let sleep = os.spawn("sleep", ["10"])
+	let res = [p.wait(), p.exitCode, p.signal, p.stdout.toString(), p.stderr.toString(), sleep.wait(10)]
+	sleep.kill("SIGTERM")
+	sleep.wait()
+	let echo = os.spawn("echo", ["b", "a"], {stdout: "pipe"})
+	let tr = os.spawn("tr", [" ", "\n"], {stdin: echo})
+	tr.wait()
+	append(res, sleep.exitCode, sleep.signal, echo.wait(), tr.stdout.toString())
+	try { os.spawn("gscript-no-such-command") } catch (e) { append(res, e.kind) }
+	return res
+}`, []interface{}{true, int64(3), nil, "a\n", "in", false, int64(-1), "SIGTERM", true, "b\na\n", "NotExist"}},
+	{"class extends", `
+class Animal {
+	__self(name) { this.name = name }
+	speak() { return this.name + " speaks" }
+	describe() { return this.speak() + "!" }
+}
+class Dog extends Animal {
+	__self(name) { super("dog " + name) }
+	speak() { return super.speak() + " woof" }
+}
+class Puppy extends Dog {}
+func test() {
+	let p = new Puppy("rex")
+	return p.describe()
+}`, "dog rex speaks woof!"},
+	{"class instanceof", `
+import Buffer
+class A {}
+class B extends A {}
+class MyError extends Error {
+	__self(msg) { super(msg, "MyError") }
+}
+class MyBuffer extends Buffer.Buffer {}
+func test() {
+	let b, C, e = new B, A, new MyError("oops")
+	return [b instanceof A, b instanceof C, new A instanceof B, 1 instanceof A,
+		e instanceof Error, e.kind, new MyBuffer(2) instanceof Buffer.Buffer]
+}`, []interface{}{true, true, false, false, true, "MyError", true}},
+	{"error objects", `
+import fs
+import strings
+class MyError extends Error {
+	__self(msg) {
+		Error(this, msg, "Mine")
+		this.extra = 1
+	}
+}
+func test() {
+	let res = []
+	try { fs.open("/nonexistent/x") } catch (e) {
+		append(res, e.kind, e.errno, e.builtin, strings.contains(e.message, "/nonexistent/x"))
+	}
+	let e = new MyError("oops")
+	# errors are formatted as print does by join
+	append(res, e.message, e.kind, e.extra, e instanceof Error, strings.join([e, Error("plain")], "|"))
+	return res
+}`, []interface{}{"NotExist", int64(2), "__open", true, "oops", "Mine", int64(1), true, "Mine: oops|Error: plain"}},
+	{"throw in constructor", `
+class A {
+	__self(x) { if (x) throw("bad") }
+}
+func test() {
+	let res = []
+	try { let a = [1, new A(true)] } catch (e) { append(res, e) }
+	return res
+}`, []interface{}{"bad"}},
+	{"goto out of try", `
+func test() {
+	let res, n = [], 0
+again:
+	n++
+	try {
+		try {
+			if (n < 2) goto again
+			loop (let v : [1, 2]) {
+				switch (v) {
+				case 2:
+					goto end
+				}
+			}
+		} finally {
+			append(res, "inner" + n)
+		}
+	} finally {
+		append(res, "outer" + n)
+	}
+	append(res, "skipped")
+end:
+	return res
+}`, []interface{}{"inner1", "outer1", "inner2", "outer2"}},
+	{"goto out of nested blocks", `
+func test() {
+	for (let a = 0; a < 2; a++) {
+		for (let b = 0; b < 2; b++) {
+			if (b == 1) goto out
+		}
+	}
+out:
+	let arr = [1, 2]
+	loop (let x : [1]) {
+		loop (let y : [2]) goto out2
+	}
+out2:
+	let res = []
+	loop (let v : arr) append(res, v)
+	return res
+}`, []interface{}{int64(1), int64(2)}},
+	{"stack of thrown values", `
+import json
+func fail() { throw(Error("bad")) }
+func test() {
+	let res = []
+	try { throw({a: 1}) } catch (e) { append(res, json.stringify(e)) }
+	try { fail() } catch (e) { append(res, e.stack != nil) }
+	return res
+}`, []interface{}{`{"a":1}`, true}},
+	{"const", `
+enum {READ = 1, WRITE = 2, EXEC = 4}
+const rw = READ | WRITE
+func test() {
+	const arr = [rw]
+	arr[0] += EXEC
+	loop (const v : [1]) append(arr, v)
+	for (const i = 0; false;) {}
+	return arr
+}`, []interface{}{int64(7), int64(1)}},
+	{"skipped return at end", `
+func ifReturn(x) { if (x) return 1 }
+func loopReturn(arr) { loop (let v : arr) return v }
+func tryReturn() { try { return 1 } catch (e) {} }
+func test() {
+	return [ifReturn(true), ifReturn(false), loopReturn([2]), loopReturn([]), tryReturn()]
+}`, []interface{}{int64(1), nil, int64(2), nil, int64(1)}},
+}
+
+func TestScripts(t *testing.T) {
+	for _, tt := range scriptTests {
+		v := newVM(t, tt.code)
+		if err := v.Run(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		rets, err := v.Call("test")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(rets) != 1 || !reflect.DeepEqual(rets[0], tt.want) {
+			t.Fatalf("%s: test returns %v, want %v", tt.name, rets, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"fmt"
+	"gscript/vm"
+	"gscript/vm/types"
+)
+
+// ToValue converts Go value v to script value:
+//
+//	nil, bool, string                 ->  as it is
+//	int, int8, ..., uint64            ->  Number(int64)
+//	float32, float64                  ->  Number(float64)
+//	[]byte                            ->  Buffer
+//	[]interface{}                     ->  Array
+//	map[string]interface{}            ->  Object
+//	Func                              ->  Function
+//
+// Values returned by FromValue are also accepted.
+func ToValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, int64, float64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case []byte:
+		return types.NewBuffer(v), nil
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i := range v {
+			val, err := ToValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = val
+		}
+		return types.NewArray(arr), nil
+	case map[string]interface{}:
+		obj := types.NewObjectN(len(v))
+		for k := range v {
+			val, err := ToValue(v[k])
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(k, val)
+		}
+		return obj, nil
+	case map[interface{}]interface{}:
+		obj := types.NewObjectN(len(v))
+		for k := range v {
+			key, err := ToValue(k)
+			if err != nil {
+				return nil, err
+			}
+			val, err := ToValue(v[k])
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, val)
+		}
+		return obj, nil
+	case Func:
+		return newHostFunc("<host>", v), nil
+	case func(Args) ([]interface{}, error):
+		return newHostFunc("<host>", v), nil
+	case *types.Array, *types.Object, *types.Buffer, *types.Closure, *types.File:
+		return v, nil
+	}
+	// builtin or host functions got from script
+	if vm.TypeOf(v) == "Builtin" {
+		return v, nil
+	}
+	return nil, fmt.Errorf("can not convert %T to script value", v)
+}
+
+// FromValue converts script value v to Go value:
+//
+//	Nil, Boolean, String  ->  nil, bool, string
+//	Number                ->  int64 or float64
+//	Buffer                ->  []byte
+//	Array                 ->  []interface{}
+//	Object                ->  map[interface{}]interface{}
+//
+// Other values such as functions and files are returned as they are.
+func FromValue(v interface{}) interface{} {
+	return fromValue(v, make(map[interface{}]interface{}))
+}
+
+// converted records arrays and objects having been converted, for values referring to themselves
+func fromValue(v interface{}, converted map[interface{}]interface{}) interface{} {
+	switch v := v.(type) {
+	case *types.Buffer:
+		return v.Data
+	case *types.Array:
+		if res, ok := converted[v]; ok {
+			return res
+		}
+		arr := make([]interface{}, len(v.Data))
+		converted[v] = arr
+		for i := range v.Data {
+			arr[i] = fromValue(v.Data[i], converted)
+		}
+		return arr
+	case *types.Object:
+		if res, ok := converted[v]; ok {
+			return res
+		}
+		m := make(map[interface{}]interface{}, v.KVCount())
+		converted[v] = m
+		v.ForEach(func(k, val interface{}) {
+			m[k] = fromValue(val, converted)
+		})
+		return m
+	}
+	return v
+}
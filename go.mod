@@ -2,9 +2,14 @@ module gscript
 
 go 1.17
 
-require github.com/spf13/cobra v1.4.0
+require (
+	github.com/peterh/liner v1.2.2
+	github.com/spf13/cobra v1.4.0
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/mattn/go-runewidth v0.0.3 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.0.0-20211117180635-dee7805ff2e1 // indirect
 )
@@ -0,0 +1,141 @@
+package proto
+
+// BuiltinFuncs are names of builtin functions, INS_LOAD_BUILTIN refers to a builtin function by
+// its index. Append new functions to the end so that compiled protos stay valid.
+var BuiltinFuncs = []string{
+	"print",
+	"len",
+	"append",
+	"sub",
+	"type",
+	"delete",
+	"clone",
+	"__buffer_new",
+	"__buffer_readNumber",
+	"__buffer_writeNumber",
+	"__buffer_toString",
+	"__buffer_slice",
+	"__buffer_concat",
+	"__buffer_copy",
+	"__buffer_from",
+	"__open",
+	"__read",
+	"__write",
+	"__close",
+	"__seek",
+	"__remove",
+	"__fchmod",
+	"__chmod",
+	"__fchown",
+	"__chown",
+	"__fchdir",
+	"__chdir",
+	"__fstat",
+	"__stat",
+	"__rename",
+	"__mkdir",
+	"__exit",
+	"__getenv",
+	"__setenv",
+	"__readdir",
+	"__freaddir",
+	"throw",
+	"__args",
+	"__getegid",
+	"__geteuid",
+	"__getgid",
+	"__getpid",
+	"__getppid",
+	"__getuid",
+	"__exec",
+	"Error",
+	"chars",
+	"codePoints",
+	"__strings_split",
+	"__strings_join",
+	"__strings_trim",
+	"__strings_trimPrefix",
+	"__strings_trimSuffix",
+	"__strings_replace",
+	"__strings_contains",
+	"__strings_index",
+	"__strings_lastIndex",
+	"__strings_hasPrefix",
+	"__strings_hasSuffix",
+	"__strings_upper",
+	"__strings_lower",
+	"__strings_repeat",
+	"__strings_pad",
+	"__strings_fields",
+	"__strings_parseInt",
+	"__strings_parseFloat",
+	"__strings_toFixed",
+	"__arrays_sort",
+	"__arrays_map",
+	"__arrays_filter",
+	"__arrays_reduce",
+	"__arrays_find",
+	"__arrays_indexOf",
+	"__arrays_reverse",
+	"__arrays_splice",
+	"__arrays_insert",
+	"__arrays_removeAt",
+	"__arrays_concat",
+	"__arrays_flat",
+	"__json_parse",
+	"__json_stringify",
+	"__math_float",
+	"__math_float2",
+	"__math_abs",
+	"__math_round",
+	"__math_pow",
+	"__math_exact",
+	"__math_min",
+	"__math_max",
+	"__math_toInt",
+	"__math_toFloat",
+	"__math_isNaN",
+	"__math_isInf",
+	"__time_now",
+	"__time_init",
+	"__time_unix",
+	"__time_date",
+	"__time_parse",
+	"__time_format",
+	"__time_add",
+	"__time_addDate",
+	"__time_sub",
+	"__time_since",
+	"__time_compare",
+	"__time_in",
+	"__time_sleep",
+	"__time_parseDuration",
+	"__time_fmtDuration",
+	"__regexp_compile",
+	"__regexp_quote",
+	"__regexp_source",
+	"__regexp_test",
+	"__regexp_find",
+	"__regexp_findAll",
+	"__regexp_findIndex",
+	"__regexp_match",
+	"__regexp_matchAll",
+	"__regexp_groups",
+	"__regexp_replace",
+	"__regexp_split",
+	"__path_join",
+	"__path_clean",
+	"__path_dir",
+	"__path_base",
+	"__path_ext",
+	"__path_abs",
+	"__path_rel",
+	"__path_split",
+	"__path_isAbs",
+	"__path_match",
+	"__glob",
+	"__walk",
+	"__spawn",
+	"__process_wait",
+	"__process_kill",
+}
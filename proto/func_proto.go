@@ -1,11 +1,35 @@
 package proto
 
-import "gscript/compiler/ast"
+import (
+	"gscript/compiler/ast"
+	"sort"
+)
 
 type BasicInfo struct {
 	VaArgs     bool
 	Parameters []ast.Parameter
 	Text       []byte
+	LineTable  []LineInfo
+	FilePath   string // not serialized, filled in by VM when loading protos
+	Name       string // not serialized, filled in by VM when loading protos
+}
+
+// instructions from Pc to Pc of next LineInfo are generated by source code at Line:Column
+type LineInfo struct {
+	Pc     uint32
+	Line   uint32
+	Column uint32
+}
+
+// search source position of instruction at pc, return zero values if not found
+func SearchLineTable(table []LineInfo, pc uint32) (line, column uint32) {
+	i := sort.Search(len(table), func(i int) bool {
+		return table[i].Pc > pc
+	})
+	if i == 0 {
+		return 0, 0
+	}
+	return table[i-1].Line, table[i-1].Column
 }
 
 type FuncProto struct {
@@ -22,5 +46,6 @@ type UpValuePtr struct {
 
 type AnonymousFuncProto struct {
 	Info     *BasicInfo
+	Name     string // class name if it is a constructor, otherwise empty
 	UpValues []UpValuePtr
 }
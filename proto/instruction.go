@@ -73,4 +73,15 @@ const (
 	INS_CALL
 	INS_RETURN
 	INS_TRY
+	INS_CALL_FINALLY
+	INS_END_FINALLY
+	INS_LOAD_HOST
+	INS_ITER_NEW
+	INS_ITER_NEXT
+	INS_ITER_UNPACK
+	INS_NEW_OBJECT
+	INS_INHERIT
+	INS_CALL_SUPER
+	INS_INSTANCEOF
+	INS_CONCAT
 )
@@ -14,7 +14,7 @@ import (
 const (
 	magicNumber       = 0x00686a6c
 	VersionMajor byte = 0
-	VersionMinor byte = 5
+	VersionMinor byte = 7
 )
 
 const (
@@ -32,6 +32,7 @@ type Proto struct {
 	Funcs          []FuncProto
 	AnonymousFuncs []AnonymousFuncProto
 	Text           []byte
+	LineTable      []LineInfo
 }
 
 type Header struct {
@@ -99,14 +100,16 @@ func writeProto(w *bytes.Buffer, proto *Proto) {
 	writeFuncs(w, proto.Funcs)
 	writeAnonymousFuncs(w, proto.AnonymousFuncs)
 	writeText(w, proto.Text)
+	writeLineTable(w, proto.LineTable)
 }
 
 // [funcsCnt:4B] [funcs]
-// func:	[UpValueCnt:4B] [UpValues] [BasicInfo]
+// func:	[name] [UpValueCnt:4B] [UpValues] [BasicInfo]
 // UpValue: [DirectDependent:1B] [Index:4B]
 func writeAnonymousFuncs(w *bytes.Buffer, funcs []AnonymousFuncProto) {
 	writeUint32(w, uint32(len(funcs)))
 	for _, _func := range funcs {
+		writeString(w, _func.Name)
 		writeUint32(w, uint32(len(_func.UpValues)))
 		for _, upvalue := range _func.UpValues {
 			if upvalue.DirectDependent {
@@ -128,6 +131,9 @@ func readAnonymousFuncs(r *bufio.Reader) (funcs []AnonymousFuncProto, err error)
 	funcs = make([]AnonymousFuncProto, 0, funcCnt)
 	for i := 0; i < int(funcCnt); i++ {
 		var _func AnonymousFuncProto
+		if _func.Name, err = readString(r); err != nil {
+			return nil, err
+		}
 		upvalueCnt, err := readUint32(r)
 		if err != nil {
 			return nil, err
@@ -205,7 +211,7 @@ func readFuncs(r *bufio.Reader) (funcs []FuncProto, err error) {
 	return
 }
 
-// BaiscInfo:	[VaArgs:1B] [parametersCnt:4B] [parameters] [textLen:4B] [text:[]byte]
+// BaiscInfo:	[VaArgs:1B] [parametersCnt:4B] [parameters] [textLen:4B] [text:[]byte] [lineTable]
 // parameter:	[nameLength:4B] [name:string] [Default:consts]
 func writeBasicInfo(w *bytes.Buffer, info *BasicInfo) {
 	if info.VaArgs {
@@ -219,6 +225,7 @@ func writeBasicInfo(w *bytes.Buffer, info *BasicInfo) {
 		writeConst(w, par.Default)
 	}
 	writeText(w, info.Text)
+	writeLineTable(w, info.LineTable)
 }
 
 func readBasicInfo(r *bufio.Reader) (info *BasicInfo, err error) {
@@ -247,6 +254,10 @@ func readBasicInfo(r *bufio.Reader) (info *BasicInfo, err error) {
 		})
 	}
 	info.Text, err = readText(r)
+	if err != nil {
+		return nil, err
+	}
+	info.LineTable, err = readLineTable(r)
 	return
 }
 
@@ -265,6 +276,37 @@ func readText(r *bufio.Reader) ([]byte, error) {
 	return text, err
 }
 
+// lineTable:	[lineInfoCnt:4B] [lineInfos]
+// lineInfo:	[pc:4B] [line:4B] [column:4B]
+func writeLineTable(w *bytes.Buffer, table []LineInfo) {
+	writeUint32(w, uint32(len(table)))
+	for _, info := range table {
+		writeUint32(w, info.Pc)
+		writeUint32(w, info.Line)
+		writeUint32(w, info.Column)
+	}
+}
+
+func readLineTable(r *bufio.Reader) (table []LineInfo, err error) {
+	cnt, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	table = make([]LineInfo, cnt)
+	for i := range table {
+		if table[i].Pc, err = readUint32(r); err != nil {
+			return nil, err
+		}
+		if table[i].Line, err = readUint32(r); err != nil {
+			return nil, err
+		}
+		if table[i].Column, err = readUint32(r); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
 // Write count(4B) of constants at first, then write all constants into w,
 // every kind of constant will be organized like following:
 // string: [type:1B](0) [length:4B] [values:len(string)]
@@ -317,8 +359,7 @@ func writeConst(w *bytes.Buffer, constant interface{}) {
 			w.WriteByte(typeFalse)
 		}
 	default:
-		fmt.Printf("writing invalid constant type\n")
-		os.Exit(0)
+		panic(fmt.Sprintf("writing invalid constant type %T", val))
 	}
 }
 
@@ -437,6 +478,10 @@ func ReadProto(r *bufio.Reader) (p Proto, err error) {
 		return
 	}
 	p.Text, err = readText(r)
+	if err != nil {
+		return
+	}
+	p.LineTable, err = readLineTable(r)
 	return
 }
 
@@ -1,8 +1,13 @@
 class Buffer{
     __self(cap, str) {
+        if (str != nil) {
+            # the buffer holds bytes of str, count of which may be larger than len(str)
+            this._buffer = __buffer_from(str);
+            this._cap = len(this._buffer);
+            return;
+        }
         if (cap == nil) return;
-        if (str != nil) this._buffer = __buffer_from(str);
-        else this._buffer = __buffer_new(cap);
+        this._buffer = __buffer_new(cap);
         this._cap = cap;
     }
     cap() {
@@ -99,11 +104,12 @@ class Buffer{
 }
 
 export {
+    Buffer: Buffer,
     alloc: func(cap) {
         return new Buffer(cap);
     },
     from: func(str) {
-        return new Buffer(len(str),str);
+        return new Buffer(nil, str);
     },
     concat: func(buf1, buf2) {
         let buf = new Buffer;
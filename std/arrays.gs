@@ -0,0 +1,14 @@
+export {
+    sort: __arrays_sort,
+    map: __arrays_map,
+    filter: __arrays_filter,
+    reduce: __arrays_reduce,
+    find: __arrays_find,
+    indexOf: __arrays_indexOf,
+    reverse: __arrays_reverse,
+    splice: __arrays_splice,
+    insert: __arrays_insert,
+    removeAt: __arrays_removeAt,
+    concat: __arrays_concat,
+    flat: __arrays_flat,
+}
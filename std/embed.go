@@ -13,12 +13,19 @@ var ProtoFiles embed.FS
 const ProtoSuffix string = ".gsproto"
 
 var StdLibMap = map[string]uint32{
-	"Buffer": 0,
-	"fs":     1,
-	"os":     2,
+	"Buffer":  0,
+	"fs":      1,
+	"os":      2,
+	"strings": 3,
+	"arrays":  4,
+	"json":    5,
+	"math":    6,
+	"time":    7,
+	"regexp":  8,
+	"path":    9,
 }
 
-var stdLibs = []string{"Buffer", "fs", "os"}
+var stdLibs = []string{"Buffer", "fs", "os", "strings", "arrays", "json", "math", "time", "regexp", "path"}
 
 func GetLibNameByProtoNum(num uint32) string {
 	return stdLibs[num]
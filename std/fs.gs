@@ -14,8 +14,11 @@ class File{
     }
     # data is a Buffer or String
     write(data, size=-1) {
+        # size of String is count of bytes
         if (type(data) != "String") {
             data = data._buffer;
+        } else {
+            data = __buffer_from(data);
         }
         size = size == -1 ? len(data) : size;
         return __write(this._file, data, size);
@@ -58,6 +61,7 @@ class File{
 }
 
 export {
+    File: File,
     open: func(path, flag="r", mode=0664) {
         let file = __open(path, flag, mode);
         return new File(file);
@@ -95,4 +99,12 @@ export {
     readDir: func(path) {
         return __readdir(path);
     },
+    glob: func(pattern) {
+        return __glob(pattern);
+    },
+    # cb(path, stat) is called for every file under root including root itself,
+    # returning false for a directory skips it
+    walk: func(root, cb) {
+        __walk(root, cb);
+    },
 }
\ No newline at end of file
@@ -0,0 +1,4 @@
+export {
+    parse: __json_parse,
+    stringify: __json_stringify,
+}
@@ -0,0 +1,90 @@
+export {
+    MAX_INT: 9223372036854775807,
+    MIN_INT: -9223372036854775807 - 1,
+    Inf: 1.0 / 0,
+    NaN: 0.0 / 0,
+    PI: 3.141592653589793,
+    E: 2.718281828459045,
+    abs: __math_abs,
+    floor: func(x) {
+        return __math_round(x, "floor");
+    },
+    ceil: func(x) {
+        return __math_round(x, "ceil");
+    },
+    round: func(x) {
+        return __math_round(x, "round");
+    },
+    trunc: func(x) {
+        return __math_round(x, "trunc");
+    },
+    toInt: __math_toInt,
+    toFloat: __math_toFloat,
+    pow: __math_pow,
+    sqrt: func(x) {
+        return __math_float(x, "sqrt");
+    },
+    cbrt: func(x) {
+        return __math_float(x, "cbrt");
+    },
+    exp: func(x) {
+        return __math_float(x, "exp");
+    },
+    log: func(x) {
+        return __math_float(x, "log");
+    },
+    log2: func(x) {
+        return __math_float(x, "log2");
+    },
+    log10: func(x) {
+        return __math_float(x, "log10");
+    },
+    sin: func(x) {
+        return __math_float(x, "sin");
+    },
+    cos: func(x) {
+        return __math_float(x, "cos");
+    },
+    tan: func(x) {
+        return __math_float(x, "tan");
+    },
+    asin: func(x) {
+        return __math_float(x, "asin");
+    },
+    acos: func(x) {
+        return __math_float(x, "acos");
+    },
+    atan: func(x) {
+        return __math_float(x, "atan");
+    },
+    sinh: func(x) {
+        return __math_float(x, "sinh");
+    },
+    cosh: func(x) {
+        return __math_float(x, "cosh");
+    },
+    tanh: func(x) {
+        return __math_float(x, "tanh");
+    },
+    atan2: func(y, x) {
+        return __math_float2(y, x, "atan2");
+    },
+    hypot: func(x, y) {
+        return __math_float2(x, y, "hypot");
+    },
+    min: __math_min,
+    max: __math_max,
+    addExact: func(a, b) {
+        return __math_exact(a, b, "add");
+    },
+    subExact: func(a, b) {
+        return __math_exact(a, b, "sub");
+    },
+    mulExact: func(a, b) {
+        return __math_exact(a, b, "mul");
+    },
+    isNaN: __math_isNaN,
+    isInf: func(x, sign=0) {
+        return __math_isInf(x, sign);
+    },
+}
@@ -1,3 +1,62 @@
+import Buffer;
+import fs;
+
+# ends of pipes of processes
+class Pipe extends fs.File {}
+
+# captured output of processes
+class Output extends Buffer.Buffer {}
+
+# stdout and stderr are captured into Buffers by default, they are set when the process exits
+class Process {
+    # stdin, stdout and stderr of opts are unwrapped by spawn
+    __self(cmd, args, opts) {
+        let upstream, stdin = nil, opts.stdin;
+        if (stdin instanceof Process) {
+            # read stdout of upstream process
+            upstream = stdin;
+            stdin = stdin.stdout._file;
+        }
+        if (opts.input != nil)
+            stdin = type(opts.input) == "String" ? __buffer_from(opts.input) : opts.input._buffer;
+        let stdout, stderr = opts.stdout, opts.stderr;
+        if (stdout == nil) stdout = "capture";
+        if (stderr == nil) stderr = "capture";
+        let proc, pid, i, o, e = __spawn(cmd, args, opts.cwd, opts.env, stdin, stdout, stderr);
+        if (upstream != nil) {
+            # the pipe is owned by this process now
+            upstream.stdout.close();
+            upstream.stdout = nil;
+        }
+        this._proc = proc;
+        this.pid = pid;
+        if (i != nil) this.stdin = new Pipe(i);
+        if (o != nil) this.stdout = new Pipe(o);
+        if (e != nil) this.stderr = new Pipe(e);
+    }
+    # return false if timeout(milliseconds) is reached before the process exits
+    wait(timeout=-1) {
+        let exited, code, signal, stdout, stderr = __process_wait(this._proc, timeout);
+        if (!exited) return false;
+        this.exitCode = code;
+        this.signal = signal;
+        if (stdout != nil) {
+            this.stdout = new Output;
+            this.stdout._buffer = stdout;
+            this.stdout._cap = len(stdout);
+        }
+        if (stderr != nil) {
+            this.stderr = new Output;
+            this.stderr._buffer = stderr;
+            this.stderr._cap = len(stderr);
+        }
+        return true;
+    }
+    kill(signal="SIGKILL") {
+        __process_kill(this._proc, signal);
+    }
+}
+
 export {
     chdir: func(path) {
         __chdir(path);
@@ -35,4 +94,16 @@ export {
     exec: func(cmd,...args) {
         return __exec(cmd, args);
     },
-}
\ No newline at end of file
+    Process: Process,
+    spawn: func(cmd, args, opts) {
+        if (args == nil) args = [];
+        if (opts == nil) opts = {};
+        # pass underlying files of fs.File to the process
+        let stdin, stdout, stderr = opts.stdin, opts.stdout, opts.stderr;
+        if (stdin instanceof fs.File) stdin = stdin._file;
+        if (stdout instanceof fs.File) stdout = stdout._file;
+        if (stderr instanceof fs.File) stderr = stderr._file;
+        return new Process(cmd, args, {cwd: opts.cwd, env: opts.env, input: opts.input,
+            stdin: stdin, stdout: stdout, stderr: stderr});
+    },
+}
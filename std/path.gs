@@ -0,0 +1,30 @@
+export {
+    join: __path_join,
+    clean: func(path) {
+        return __path_clean(path);
+    },
+    dir: func(path) {
+        return __path_dir(path);
+    },
+    base: func(path) {
+        return __path_base(path);
+    },
+    ext: func(path) {
+        return __path_ext(path);
+    },
+    abs: func(path) {
+        return __path_abs(path);
+    },
+    rel: func(base, target) {
+        return __path_rel(base, target);
+    },
+    split: func(path) {
+        return __path_split(path);
+    },
+    isAbs: func(path) {
+        return __path_isAbs(path);
+    },
+    match: func(pattern, name) {
+        return __path_match(pattern, name);
+    },
+}
@@ -0,0 +1,14 @@
+export {
+    compile: __regexp_compile,
+    quote: __regexp_quote,
+    source: __regexp_source,
+    test: __regexp_test,
+    find: __regexp_find,
+    findAll: __regexp_findAll,
+    findIndex: __regexp_findIndex,
+    match: __regexp_match,
+    matchAll: __regexp_matchAll,
+    groups: __regexp_groups,
+    replace: __regexp_replace,
+    split: __regexp_split,
+}
@@ -0,0 +1,68 @@
+export {
+    split: func(str, sep, n=-1) {
+        return __strings_split(str, sep, n);
+    },
+    join: func(arr, sep="") {
+        return __strings_join(arr, sep);
+    },
+    trim: func(str, cutset) {
+        return __strings_trim(str, cutset, "both");
+    },
+    trimLeft: func(str, cutset) {
+        return __strings_trim(str, cutset, "left");
+    },
+    trimRight: func(str, cutset) {
+        return __strings_trim(str, cutset, "right");
+    },
+    trimPrefix: func(str, prefix) {
+        return __strings_trimPrefix(str, prefix);
+    },
+    trimSuffix: func(str, suffix) {
+        return __strings_trimSuffix(str, suffix);
+    },
+    replace: func(str, old, replacement, n=-1) {
+        return __strings_replace(str, old, replacement, n);
+    },
+    contains: func(str, substr) {
+        return __strings_contains(str, substr);
+    },
+    index: func(str, substr) {
+        return __strings_index(str, substr);
+    },
+    lastIndex: func(str, substr) {
+        return __strings_lastIndex(str, substr);
+    },
+    hasPrefix: func(str, prefix) {
+        return __strings_hasPrefix(str, prefix);
+    },
+    hasSuffix: func(str, suffix) {
+        return __strings_hasSuffix(str, suffix);
+    },
+    upper: func(str) {
+        return __strings_upper(str);
+    },
+    lower: func(str) {
+        return __strings_lower(str);
+    },
+    repeat: func(str, count) {
+        return __strings_repeat(str, count);
+    },
+    padStart: func(str, length, pad=" ") {
+        return __strings_pad(str, length, pad, true);
+    },
+    padEnd: func(str, length, pad=" ") {
+        return __strings_pad(str, length, pad, false);
+    },
+    fields: func(str) {
+        return __strings_fields(str);
+    },
+    parseInt: func(str, base=10) {
+        return __strings_parseInt(str, base);
+    },
+    parseFloat: func(str) {
+        return __strings_parseFloat(str);
+    },
+    toFixed: func(num, digits=0) {
+        return __strings_toFixed(num, digits);
+    },
+}
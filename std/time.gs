@@ -0,0 +1,80 @@
+# fields of Time are a snapshot of the instant, assigning them does not change the instant
+# that methods work on, and methods return new instances
+class Time {
+    __self(t) {
+        __time_init(this, t);
+    }
+    format(layout="2006-01-02T15:04:05Z07:00") {
+        return __time_format(this._time, layout);
+    }
+    # d is a duration in nanoseconds
+    add(d) {
+        return new Time(__time_add(this._time, d));
+    }
+    addDate(years, months=0, days=0) {
+        return new Time(__time_addDate(this._time, years, months, days));
+    }
+    # return duration of this - t
+    sub(t) {
+        return __time_sub(this._time, t._time);
+    }
+    before(t) {
+        return __time_compare(this._time, t._time) < 0;
+    }
+    after(t) {
+        return __time_compare(this._time, t._time) > 0;
+    }
+    equal(t) {
+        return __time_compare(this._time, t._time) == 0;
+    }
+    # zone = Local, UTC or name of IANA time zone such as Asia/Shanghai
+    in(zone) {
+        return new Time(__time_in(this._time, zone));
+    }
+    utc() {
+        return this.in("UTC");
+    }
+    local() {
+        return this.in("Local");
+    }
+}
+
+export {
+    Time: Time,
+    NANOSECOND: 1,
+    MICROSECOND: 1000,
+    MILLISECOND: 1000000,
+    SECOND: 1000000000,
+    MINUTE: 60000000000,
+    HOUR: 3600000000000,
+    RFC3339: "2006-01-02T15:04:05Z07:00",
+    RFC3339_NANO: "2006-01-02T15:04:05.999999999Z07:00",
+    RFC1123: "Mon, 02 Jan 2006 15:04:05 MST",
+    DATETIME: "2006-01-02 15:04:05",
+    DATE: "2006-01-02",
+    TIME: "15:04:05",
+    now: func() {
+        return new Time(__time_now());
+    },
+    unix: func(sec, nsec=0) {
+        return new Time(__time_unix(sec, nsec));
+    },
+    date: func(year, month, day, hour=0, minute=0, second=0, nanosecond=0, zone="Local") {
+        return new Time(__time_date(year, month, day, hour, minute, second, nanosecond, zone));
+    },
+    parse: func(layout, str, zone="UTC") {
+        return new Time(__time_parse(layout, str, zone));
+    },
+    since: func(t) {
+        return __time_since(t._time);
+    },
+    sleep: func(ms) {
+        __time_sleep(ms);
+    },
+    parseDuration: func(str) {
+        return __time_parseDuration(str);
+    },
+    formatDuration: func(d) {
+        return __time_fmtDuration(d);
+    },
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"gscript/compiler/ast"
 	"gscript/compiler/codegen"
 	"gscript/compiler/lexer"
 	"gscript/compiler/parser"
@@ -27,7 +28,16 @@ func main() {
 	}
 }
 
-func complieStdLib(stdlib string) error {
+func complieStdLib(stdlib string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(ast.ErrorList)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
 	source, target := path.Join(os.Args[1], stdlib+".gs"), path.Join(os.Args[1], stdlib+".gsproto")
 	protoNum := std.StdLibMap[stdlib]
 
@@ -54,7 +64,7 @@ func complieStdLib(stdlib string) error {
 			})
 		}
 	}
-	_proto := codegen.Gen(parser, prog, imports, protoNum)
+	_proto := codegen.Gen(parser, prog, imports, protoNum, nil)
 	_proto.FilePath = stdlib
 
 	file, err := os.OpenFile(target, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0664)
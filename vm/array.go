@@ -0,0 +1,306 @@
+package vm
+
+import (
+	"fmt"
+	"gscript/vm/types"
+	"sort"
+)
+
+// builtin functions of std library arrays, callbacks are called with the element and its index
+
+func arrayArg(arg interface{}, vm *VM) *types.Array {
+	arr, ok := arg.(*types.Array)
+	vm.assert(ok)
+	return arr
+}
+
+// arg1: Array, arg2: optional callback(a, b) which returns true or a negative number if a is less than b
+// return: the array itself, which is sorted in ascending order stably
+func builtinArraySort(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1 || argCnt == 2)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	less := func(a, b interface{}) bool { return lessThan(vm, a, b) }
+	failed := false
+	if argCnt == 2 && args[1] != nil {
+		less = func(a, b interface{}) bool {
+			if failed {
+				return false
+			}
+			ret, ok := vm.callback(args[1], a, b)
+			if !ok {
+				// stop comparing, the array is left partially sorted
+				failed = true
+				return false
+			}
+			switch ret := ret.(type) {
+			case bool:
+				return ret
+			case int64:
+				return ret < 0
+			case float64:
+				return ret < 0
+			default:
+				vm.exit("compare function of sort should return a Boolean or Number, but got a %s", getType(ret))
+			}
+			return false
+		}
+	}
+	sort.SliceStable(arr.Data, func(i, j int) bool {
+		return less(arr.Data[i], arr.Data[j])
+	})
+	if failed {
+		return 0
+	}
+	push(vm, arr)
+	return 1
+}
+
+// natural order of Numbers and Strings
+func lessThan(vm *VM, a, b interface{}) bool {
+	if s1, ok := a.(string); ok {
+		if s2, ok := b.(string); ok {
+			return s1 < s2
+		}
+	}
+	n1, ok1 := toFloat(a)
+	n2, ok2 := toFloat(b)
+	if !ok1 || !ok2 {
+		vm.exit("can not compare %s with %s", getType(a), getType(b))
+	}
+	return n1 < n2
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch val := val.(type) {
+	case int64:
+		return float64(val), true
+	case float64:
+		return val, true
+	}
+	return 0, false
+}
+
+// Numbers are equal if their values are equal, Arrays and Objects are equal only if they are the same one
+func equal(a, b interface{}) bool {
+	n1, ok1 := toFloat(a)
+	n2, ok2 := toFloat(b)
+	if ok1 && ok2 {
+		return n1 == n2
+	}
+	return a == b
+}
+
+// arg1: Array, arg2: callback(value, index)
+// return: Array of return values of callback
+func builtinArrayMap(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	data := make([]interface{}, 0, len(arr.Data))
+	for i := 0; i < len(arr.Data); i++ {
+		ret, ok := vm.callback(args[1], arr.Data[i], int64(i))
+		if !ok {
+			return 0
+		}
+		data = append(data, ret)
+	}
+	push(vm, types.NewArray(data))
+	return 1
+}
+
+// arg1: Array, arg2: callback(value, index)
+// return: Array of elements for which callback returns true
+func builtinArrayFilter(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	data := make([]interface{}, 0)
+	for i := 0; i < len(arr.Data); i++ {
+		v := arr.Data[i]
+		ret, ok := vm.callback(args[1], v, int64(i))
+		if !ok {
+			return 0
+		}
+		if getBool(ret) {
+			data = append(data, v)
+		}
+	}
+	push(vm, types.NewArray(data))
+	return 1
+}
+
+// arg1: Array, arg2: callback(accumulator, value, index), arg3: optional initial value of accumulator,
+// the first element is used if it is omitted
+// return: the last return value of callback
+func builtinArrayReduce(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2 || argCnt == 3)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	var acc interface{}
+	i := 0
+	if argCnt == 3 {
+		acc = args[2]
+	} else if len(arr.Data) > 0 {
+		acc = arr.Data[0]
+		i++
+	}
+	for ; i < len(arr.Data); i++ {
+		var ok bool
+		if acc, ok = vm.callback(args[1], acc, arr.Data[i], int64(i)); !ok {
+			return 0
+		}
+	}
+	push(vm, acc)
+	return 1
+}
+
+// arg1: Array, arg2: callback(value, index)
+// return: the first element for which callback returns true, nil if not found
+func builtinArrayFind(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	for i := 0; i < len(arr.Data); i++ {
+		v := arr.Data[i]
+		ret, ok := vm.callback(args[1], v, int64(i))
+		if !ok {
+			return 0
+		}
+		if getBool(ret) {
+			push(vm, v)
+			return 1
+		}
+	}
+	push(vm, nil)
+	return 1
+}
+
+// arg1: Array, arg2: value
+// return: index of the first element equal to value, -1 if not found
+func builtinArrayIndexOf(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	for i, v := range arrayArg(args[0], vm).Data {
+		if equal(v, args[1]) {
+			push(vm, int64(i))
+			return 1
+		}
+	}
+	push(vm, int64(-1))
+	return 1
+}
+
+// arg1: Array
+// return: the array itself, which is reversed
+func builtinArrayReverse(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	arr := arrayArg(pop(vm), vm)
+	for i, j := 0, len(arr.Data)-1; i < j; i, j = i+1, j-1 {
+		arr.Data[i], arr.Data[j] = arr.Data[j], arr.Data[i]
+	}
+	push(vm, arr)
+	return 1
+}
+
+// arg1: Array, arg2: start, arg3: count of elements to delete, arg4...: elements inserted at start
+// return: Array of deleted elements
+func builtinArraySplice(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt >= 3)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	start, count := intArg(args[1], vm), intArg(args[2], vm)
+	if start < 0 || start > len(arr.Data) || count < 0 {
+		throw(fmt.Errorf("start %d or count %d out of range of Array of length %d: %w",
+			start, count, len(arr.Data), errInvalidArg), vm)
+		return 0
+	}
+	if start+count > len(arr.Data) {
+		count = len(arr.Data) - start
+	}
+	deleted := make([]interface{}, count)
+	copy(deleted, arr.Data[start:start+count])
+	arr.Data = splice(arr.Data, start, count, args[3:])
+	push(vm, types.NewArray(deleted))
+	return 1
+}
+
+// replace count elements of data from start with vals
+func splice(data []interface{}, start, count int, vals []interface{}) []interface{} {
+	res := make([]interface{}, 0, len(data)-count+len(vals))
+	res = append(res, data[:start]...)
+	res = append(res, vals...)
+	return append(res, data[start+count:]...)
+}
+
+// arg1: Array, arg2: index, arg3...: elements to insert before index
+func builtinArrayInsert(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt >= 3)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	idx := intArg(args[1], vm)
+	if idx < 0 || idx > len(arr.Data) {
+		throw(fmt.Errorf("index %d out of range of Array of length %d: %w", idx, len(arr.Data), errInvalidArg), vm)
+		return 0
+	}
+	arr.Data = splice(arr.Data, idx, 0, args[2:])
+	return 0
+}
+
+// arg1: Array, arg2: index
+// return: the removed element
+func builtinArrayRemoveAt(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	arr := arrayArg(args[0], vm)
+	idx := intArg(args[1], vm)
+	if idx < 0 || idx >= len(arr.Data) {
+		throw(fmt.Errorf("index %d out of range of Array of length %d: %w", idx, len(arr.Data), errInvalidArg), vm)
+		return 0
+	}
+	removed := arr.Data[idx]
+	arr.Data = splice(arr.Data, idx, 1, nil)
+	push(vm, removed)
+	return 1
+}
+
+// arg1: Array, arg2...: Arrays or other values
+// return: a new Array containing elements of all Arrays, other values are added as elements
+func builtinArrayConcat(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt >= 1)
+	args := popArgs(argCnt, vm)
+	data := append([]interface{}{}, arrayArg(args[0], vm).Data...)
+	for _, arg := range args[1:] {
+		if arr, ok := arg.(*types.Array); ok {
+			data = append(data, arr.Data...)
+		} else {
+			data = append(data, arg)
+		}
+	}
+	push(vm, types.NewArray(data))
+	return 1
+}
+
+// arg1: Array, arg2: depth, default 1
+// return: a new Array with elements of sub-arrays concatenated into it recursively up to depth
+func builtinArrayFlat(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1 || argCnt == 2)
+	args := popArgs(argCnt, vm)
+	depth := 1
+	if argCnt == 2 {
+		depth = intArg(args[1], vm)
+	}
+	push(vm, types.NewArray(flat(make([]interface{}, 0), arrayArg(args[0], vm).Data, depth)))
+	return 1
+}
+
+func flat(dst, data []interface{}, depth int) []interface{} {
+	for _, v := range data {
+		if arr, ok := v.(*types.Array); ok && depth > 0 {
+			dst = flat(dst, arr.Data, depth-1)
+		} else {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
@@ -3,13 +3,18 @@ package vm
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"gscript/proto"
 	"gscript/vm/types"
 	"io"
 	"io/fs"
 	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 )
 
 type builtinFunc struct {
@@ -21,53 +26,161 @@ func GetBuiltinFuncNameByNum(num uint32) string {
 	return builtinFuncs[num].name
 }
 
-// if modify the following, should modify builtinFuncs in context.go too
-var builtinFuncs = []builtinFunc{
-	{builtinPrint, "print"},
-	{builtinLen, "len"},
-	{builtinAppend, "append"},
-	{builtinSub, "sub"},
-	{builtinType, "type"},
-	{builtinDelete, "delete"},
-	{builtinClone, "clone"},
-	{builtinBufferNew, "__buffer_new"},
-	{builtinBufferReadNumber, "__buffer_readNumber"},
-	{builtinBufferWriteNumber, "__buffer_writeNumber"},
-	{builtinBufferToString, "__buffer_toString"},
-	{builtinBufferSlice, "__buffer_slice"},
-	{builtinBufferConcat, "__buffer_concat"},
-	{builtinBufferCopy, "__buffer_copy"},
-	{builtinBufferFrom, "__buffer_from"},
-	{builtinOpen, "__open"},
-	{builtinRead, "__read"},
-	{builtinWrite, "__write"},
-	{builtinClose, "__close"},
-	{builtinSeek, "__seek"},
-	{builtinRemove, "__remove"},
-	{builtinFChmod, "__fchmod"},
-	{builtinChmod, "__chmod"},
-	{builtinFChown, "__fchown"},
-	{builtinChown, "__chown"},
-	{builtinFChdir, "__fchdir"},
-	{builtinChdir, "__chdir"},
-	{builtinFStat, "__fstat"},
-	{builtinStat, "__stat"},
-	{builtinRename, "__rename"},
-	{builtinMkdir, "__mkdir"},
-	{builtinExit, "__exit"},
-	{builtinGetEnv, "__getenv"},
-	{builtinSetEnv, "__setenv"},
-	{builtinReadDir, "__readdir"},
-	{builtinFReadDir, "__freaddir"},
-	{builtinThrow, "throw"},
-	{builtinArgs, "__args"},
-	{builtinGetegid, "__getegid"},
-	{builtinGeteuid, "__geteuid"},
-	{builtinGetgid, "__getgid"},
-	{builtinGetpid, "__getpid"},
-	{builtinGetppid, "__getppid"},
-	{builtinGetuid, "__getuid"},
-	{builtinExec, "__exec"},
+// handlers of builtin functions named in proto.BuiltinFuncs
+var builtinHandlers = map[string]func(argCnt int, vm *VM) int{
+	"print":                builtinPrint,
+	"len":                  builtinLen,
+	"append":               builtinAppend,
+	"sub":                  builtinSub,
+	"type":                 builtinType,
+	"delete":               builtinDelete,
+	"clone":                builtinClone,
+	"__buffer_new":         builtinBufferNew,
+	"__buffer_readNumber":  builtinBufferReadNumber,
+	"__buffer_writeNumber": builtinBufferWriteNumber,
+	"__buffer_toString":    builtinBufferToString,
+	"__buffer_slice":       builtinBufferSlice,
+	"__buffer_concat":      builtinBufferConcat,
+	"__buffer_copy":        builtinBufferCopy,
+	"__buffer_from":        builtinBufferFrom,
+	"__open":               builtinOpen,
+	"__read":               builtinRead,
+	"__write":              builtinWrite,
+	"__close":              builtinClose,
+	"__seek":               builtinSeek,
+	"__remove":             builtinRemove,
+	"__fchmod":             builtinFChmod,
+	"__chmod":              builtinChmod,
+	"__fchown":             builtinFChown,
+	"__chown":              builtinChown,
+	"__fchdir":             builtinFChdir,
+	"__chdir":              builtinChdir,
+	"__fstat":              builtinFStat,
+	"__stat":               builtinStat,
+	"__rename":             builtinRename,
+	"__mkdir":              builtinMkdir,
+	"__exit":               builtinExit,
+	"__getenv":             builtinGetEnv,
+	"__setenv":             builtinSetEnv,
+	"__readdir":            builtinReadDir,
+	"__freaddir":           builtinFReadDir,
+	"throw":                builtinThrow,
+	"__args":               builtinArgs,
+	"__getegid":            builtinGetegid,
+	"__geteuid":            builtinGeteuid,
+	"__getgid":             builtinGetgid,
+	"__getpid":             builtinGetpid,
+	"__getppid":            builtinGetppid,
+	"__getuid":             builtinGetuid,
+	"__exec":               builtinExec,
+	"Error":                builtinError,
+	"chars":                builtinChars,
+	"codePoints":           builtinCodePoints,
+	"__strings_split":      builtinStringsSplit,
+	"__strings_join":       builtinStringsJoin,
+	"__strings_trim":       builtinStringsTrim,
+	"__strings_trimPrefix": builtinStringsTrimPrefix,
+	"__strings_trimSuffix": builtinStringsTrimSuffix,
+	"__strings_replace":    builtinStringsReplace,
+	"__strings_contains":   builtinStringsContains,
+	"__strings_index":      builtinStringsIndex,
+	"__strings_lastIndex":  builtinStringsLastIndex,
+	"__strings_hasPrefix":  builtinStringsHasPrefix,
+	"__strings_hasSuffix":  builtinStringsHasSuffix,
+	"__strings_upper":      builtinStringsUpper,
+	"__strings_lower":      builtinStringsLower,
+	"__strings_repeat":     builtinStringsRepeat,
+	"__strings_pad":        builtinStringsPad,
+	"__strings_fields":     builtinStringsFields,
+	"__strings_parseInt":   builtinStringsParseInt,
+	"__strings_parseFloat": builtinStringsParseFloat,
+	"__strings_toFixed":    builtinStringsToFixed,
+	"__arrays_sort":        builtinArraySort,
+	"__arrays_map":         builtinArrayMap,
+	"__arrays_filter":      builtinArrayFilter,
+	"__arrays_reduce":      builtinArrayReduce,
+	"__arrays_find":        builtinArrayFind,
+	"__arrays_indexOf":     builtinArrayIndexOf,
+	"__arrays_reverse":     builtinArrayReverse,
+	"__arrays_splice":      builtinArraySplice,
+	"__arrays_insert":      builtinArrayInsert,
+	"__arrays_removeAt":    builtinArrayRemoveAt,
+	"__arrays_concat":      builtinArrayConcat,
+	"__arrays_flat":        builtinArrayFlat,
+	"__json_parse":         builtinJSONParse,
+	"__json_stringify":     builtinJSONStringify,
+	"__math_float":         builtinMathFloat,
+	"__math_float2":        builtinMathFloat2,
+	"__math_abs":           builtinMathAbs,
+	"__math_round":         builtinMathRound,
+	"__math_pow":           builtinMathPow,
+	"__math_exact":         builtinMathExact,
+	"__math_min":           builtinMathMin,
+	"__math_max":           builtinMathMax,
+	"__math_toInt":         builtinMathToInt,
+	"__math_toFloat":       builtinMathToFloat,
+	"__math_isNaN":         builtinMathIsNaN,
+	"__math_isInf":         builtinMathIsInf,
+	"__time_now":           builtinTimeNow,
+	"__time_init":          builtinTimeInit,
+	"__time_unix":          builtinTimeUnix,
+	"__time_date":          builtinTimeDate,
+	"__time_parse":         builtinTimeParse,
+	"__time_format":        builtinTimeFormat,
+	"__time_add":           builtinTimeAdd,
+	"__time_addDate":       builtinTimeAddDate,
+	"__time_sub":           builtinTimeSub,
+	"__time_since":         builtinTimeSince,
+	"__time_compare":       builtinTimeCompare,
+	"__time_in":            builtinTimeIn,
+	"__time_sleep":         builtinTimeSleep,
+	"__time_parseDuration": builtinTimeParseDuration,
+	"__time_fmtDuration":   builtinTimeFormatDuration,
+	"__regexp_compile":     builtinRegexpCompile,
+	"__regexp_quote":       builtinRegexpQuote,
+	"__regexp_source":      builtinRegexpSource,
+	"__regexp_test":        builtinRegexpTest,
+	"__regexp_find":        builtinRegexpFind,
+	"__regexp_findAll":     builtinRegexpFindAll,
+	"__regexp_findIndex":   builtinRegexpFindIndex,
+	"__regexp_match":       builtinRegexpMatch,
+	"__regexp_matchAll":    builtinRegexpMatchAll,
+	"__regexp_groups":      builtinRegexpGroups,
+	"__regexp_replace":     builtinRegexpReplace,
+	"__regexp_split":       builtinRegexpSplit,
+	"__path_join":          builtinPathJoin,
+	"__path_clean":         builtinPathClean,
+	"__path_dir":           builtinPathDir,
+	"__path_base":          builtinPathBase,
+	"__path_ext":           builtinPathExt,
+	"__path_abs":           builtinPathAbs,
+	"__path_rel":           builtinPathRel,
+	"__path_split":         builtinPathSplit,
+	"__path_isAbs":         builtinPathIsAbs,
+	"__path_match":         builtinPathMatch,
+	"__glob":               builtinGlob,
+	"__walk":               builtinWalk,
+	"__spawn":              builtinSpawn,
+	"__process_wait":       builtinProcessWait,
+	"__process_kill":       builtinProcessKill,
+}
+
+// builtin functions indexed the same as proto.BuiltinFuncs
+var builtinFuncs = newBuiltinFuncs()
+
+func newBuiltinFuncs() []builtinFunc {
+	if len(builtinHandlers) != len(proto.BuiltinFuncs) {
+		panic("builtin functions of vm and proto mismatch")
+	}
+	funcs := make([]builtinFunc, len(proto.BuiltinFuncs))
+	for i, name := range proto.BuiltinFuncs {
+		handler, ok := builtinHandlers[name]
+		if !ok {
+			panic("no handler for builtin function " + name)
+		}
+		funcs[i] = builtinFunc{handler, name}
+	}
+	return funcs
 }
 
 func builtinExec(argCnt int, vm *VM) int {
@@ -83,6 +196,10 @@ func builtinExec(argCnt int, vm *VM) int {
 		vm.assert(ok)
 		args[i] = str
 	}
+	if err := vm.sandbox.checkExec(command); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	output, err := exec.Command(command, args...).Output()
 	if err != nil {
 		throw(err, vm)
@@ -146,6 +263,10 @@ func builtinMkdir(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(ok)
 	str, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkWrite(str); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	if err := os.Mkdir(str, os.FileMode(uint32(mode))); err != nil {
 		throw(err, vm)
 	}
@@ -153,28 +274,82 @@ func builtinMkdir(argCnt int, vm *VM) (retCnt int) {
 }
 
 func throw(err error, vm *VM) {
+	var exception interface{}
+	switch e := err.(type) {
+	case *RuntimeError:
+		// returned by VM.CallValue, the uncaught exception is rethrown and other errors are fatal
+		if !e.Uncaught {
+			panic(e)
+		}
+		exception = e.Exception
+	case *ExitError:
+		panic(e)
+	default:
+		exception = newBuiltinError(err, vm.curCallingBuiltin)
+	}
 	vm.builtinFuncFailed = true
-	push(vm, err.Error())
+	push(vm, exception)
 	_throw(vm)
 }
 
 func _throw(vm *VM) {
+	// position and traceback of where it is thrown, it is only needed if the exception is not
+	// caught or it is an error object whose traceback is not recorded
+	var uncaught *RuntimeError
+	obj, ok := vm.curProto.stack.Top().(*types.Object)
+	needStack := ok && obj.IsError && obj.Get("stack") == nil
+	if needStack || !vm.catching() {
+		uncaught = vm.newRuntimeError("")
+	}
 	for {
 		frame := vm.curProto.frame
+		// frames waiting for a nested call do not catch exceptions of the callee, see VM.callNested
+		if frame == vm.barrier {
+			panicUncaught(vm, uncaught)
+		}
 		tryInfos := frame.tryInfos
 		if len(tryInfos) > 0 {
-			catchAddr, varCnt := frame.popTryInfo()
-			frame.symbolTable.resizeTo(int(varCnt))
-			frame.pc = catchAddr
+			info := frame.popTryInfo()
+			frame.symbolTable.resizeTo(int(info.curVarCnt))
+			frame.pc = info.catchAddr
+			// drop values of the interrupted expression, such as the object being constructed
+			stack := vm.curProto.stack
+			exception := stack.pop()
+			stack.popN(len(stack.Buf) - info.stackSize)
+			stack.Push(exception)
 			break
 		}
 		if frame.prev == nil {
-			var buf bytes.Buffer
-			fprint(&buf, pop(vm))
-			vm.exit("uncaught exception: %s", buf.String())
+			panicUncaught(vm, uncaught)
 		}
 		vm.curProto.frame = frame.prev
 	}
+	// keep the traceback of where it was thrown first, even if it is rethrown
+	if needStack {
+		obj.Set("stack", uncaught.Traceback)
+	}
+}
+
+// whether the exception being thrown will be caught, see _throw
+func (vm *VM) catching() bool {
+	for frame := vm.curProto.frame; frame != vm.barrier; frame = frame.prev {
+		if len(frame.tryInfos) > 0 {
+			return true
+		}
+		if frame.prev == nil {
+			return false
+		}
+	}
+	return false
+}
+
+func panicUncaught(vm *VM, uncaught *RuntimeError) {
+	var buf bytes.Buffer
+	uncaught.Exception = pop(vm)
+	fprint(&buf, uncaught.Exception)
+	uncaught.Msg = "uncaught exception: " + buf.String()
+	uncaught.Uncaught = true
+	panic(uncaught)
 }
 
 func builtinThrow(argCnt int, vm *VM) (retCnt int) {
@@ -187,6 +362,10 @@ func builtinRemove(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt == 1)
 	path, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkWrite(path); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	if err := os.Remove(path); err != nil {
 		throw(err, vm)
 	}
@@ -233,6 +412,10 @@ func builtinReadDir(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt == 1)
 	pathname, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkRead(pathname); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	entrys, err := os.ReadDir(pathname)
 	if err != nil {
 		throw(err, vm)
@@ -247,6 +430,85 @@ func builtinReadDir(argCnt int, vm *VM) (retCnt int) {
 	return 1
 }
 
+// static directory part of glob pattern, which contains no meta characters
+func globRoot(pattern string) string {
+	magicChars := `*?[`
+	if runtime.GOOS != "windows" {
+		magicChars = `*?[\`
+	}
+	dir := pattern
+	for strings.ContainsAny(dir, magicChars) {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// arg1: pattern
+// return: []String, names of files matching pattern
+func builtinGlob(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	pattern, ok := pop(vm).(string)
+	vm.assert(ok)
+	if err := vm.sandbox.checkRead(globRoot(pattern)); err != nil {
+		throw(err, vm)
+		return 0
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	// symbolic links may escape from roots of sandbox
+	allowed := matches[:0]
+	for _, match := range matches {
+		if vm.sandbox.checkRead(match) == nil {
+			allowed = append(allowed, match)
+		}
+	}
+	push(vm, stringArray(allowed))
+	return 1
+}
+
+// walk is stopped because the callback raises an exception
+var errWalkStopped = errors.New("walk stopped")
+
+// arg1: root, arg2: callback(path, statObject), files are walked in lexical order and
+// symbolic links are not followed. If callback returns false for a directory, it is skipped.
+func builtinWalk(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	root, ok := args[0].(string)
+	vm.assert(ok && callable(args[1]))
+	if err := vm.sandbox.checkRead(root); err != nil {
+		throw(err, vm)
+		return 0
+	}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		ret, ok := vm.callback(args[1], path, newStat(info))
+		if !ok {
+			return errWalkStopped
+		}
+		if enter, ok := ret.(bool); ok && !enter && entry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err == errWalkStopped {
+		return 0
+	}
+	if err != nil {
+		throw(err, vm)
+	}
+	return 0
+}
+
 // arg1: key, arg2: value
 func builtinSetEnv(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt == 2)
@@ -254,6 +516,10 @@ func builtinSetEnv(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(ok)
 	key, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkEnv("setenv", key); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	if err := os.Setenv(key, value); err != nil {
 		throw(err, vm)
 		return 0
@@ -276,8 +542,7 @@ func builtinExit(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt == 1)
 	code, ok := pop(vm).(int64)
 	vm.assert(ok)
-	os.Exit(int(code))
-	return 0
+	panic(&ExitError{Code: int(code)})
 }
 
 // arg1: oldpath, arg2: newpath
@@ -287,6 +552,14 @@ func builtinRename(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(ok)
 	oldpath, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkWrite(oldpath); err != nil {
+		throw(err, vm)
+		return 0
+	}
+	if err := vm.sandbox.checkWrite(newpath); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	if err := os.Rename(oldpath, newpath); err != nil {
 		throw(err, vm)
 	}
@@ -309,6 +582,10 @@ func builtinStat(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt == 1)
 	path, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkRead(path); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	stat, err := os.Stat(path)
 	if err != nil {
 		throw(err, vm)
@@ -339,6 +616,10 @@ func builtinFChdir(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt == 1)
 	file, ok := pop(vm).(*types.File)
 	vm.assert(ok)
+	if err := vm.sandbox.checkEnv("chdir", file.File.Name()); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	err := file.File.Chdir()
 	if err != nil {
 		throw(err, vm)
@@ -356,6 +637,10 @@ func builtinFChown(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(ok)
 	file, ok := pop(vm).(*types.File)
 	vm.assert(ok)
+	if err := vm.sandbox.checkWrite(file.File.Name()); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	err := file.File.Chown(int(uid), int(gid))
 	if err != nil {
 		throw(err, vm)
@@ -371,6 +656,10 @@ func builtinFChmod(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(ok)
 	file, ok := pop(vm).(*types.File)
 	vm.assert(ok)
+	if err := vm.sandbox.checkWrite(file.File.Name()); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	err := file.File.Chmod(os.FileMode(uint32(mode)))
 	if err != nil {
 		throw(err, vm)
@@ -384,6 +673,10 @@ func builtinChdir(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt == 1)
 	path, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkEnv("chdir", path); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	err := os.Chdir(path)
 	if err != nil {
 		throw(err, vm)
@@ -401,6 +694,10 @@ func builtinChown(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(ok)
 	path, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkWrite(path); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	err := os.Chown(path, int(uid), int(gid))
 	if err != nil {
 		throw(err, vm)
@@ -416,6 +713,10 @@ func builtinChmod(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(ok)
 	file, ok := pop(vm).(string)
 	vm.assert(ok)
+	if err := vm.sandbox.checkWrite(file); err != nil {
+		throw(err, vm)
+		return 0
+	}
 	err := os.Chmod(file, os.FileMode(uint32(mode)))
 	if err != nil {
 		throw(err, vm)
@@ -558,6 +859,10 @@ func builtinOpen(argCnt int, vm *VM) (retCnt int) {
 	filepath, ok := pop(vm).(string)
 	vm.assert(ok)
 	flag := getFileFlag(flagS)
+	if err := vm.sandbox.checkOpen(filepath, flag); err != nil {
+		throw(err, vm)
+		return 0
+	}
 
 	file, err := os.OpenFile(filepath, flag, os.FileMode(uint32(mode)))
 	if err != nil {
@@ -785,8 +1090,7 @@ func builtinClone(argCnt int, vm *VM) (retCnt int) {
 		copy(arr, src.Data)
 		push(vm, types.NewArray(arr))
 	case *types.Object:
-		obj := types.NewObjectN(src.KVCount())
-		push(vm, obj.Clone())
+		push(vm, src.Clone())
 	case *types.Buffer:
 		data := make([]byte, len(src.Data))
 		copy(data, src.Data)
@@ -812,7 +1116,7 @@ func getType(v interface{}) string {
 		return "String"
 	case *types.Closure:
 		return "Closure"
-	case *builtinFunc:
+	case *builtinFunc, *method:
 		return "Builtin"
 	case *types.Object:
 		return "Object"
@@ -820,6 +1124,8 @@ func getType(v interface{}) string {
 		return "Array"
 	case *types.Buffer:
 		return "Buffer"
+	case *types.Regexp:
+		return "Regexp"
 	case int64, float64:
 		return "Number"
 	case bool:
@@ -848,10 +1154,12 @@ func builtinSub(argCnt int, vm *VM) (retCnt int) {
 	vm.curProto.stack.popN(argCnt)
 	switch target := target.(type) {
 	case string:
+		// indexes are counted in characters
+		runes := []rune(target)
 		if argCnt == 2 {
-			end = int64(len(target))
+			end = int64(len(runes))
 		}
-		push(vm, target[start:end])
+		push(vm, string(runes[start:end]))
 	case *types.Array:
 		if argCnt == 2 {
 			end = int64(len(target.Data))
@@ -864,6 +1172,34 @@ func builtinSub(argCnt int, vm *VM) (retCnt int) {
 	return 1
 }
 
+// arg1: String
+// return: Array of characters of the string
+func builtinChars(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	str, ok := pop(vm).(string)
+	vm.assert(ok)
+	chars := make([]interface{}, 0, len(str))
+	for _, r := range str {
+		chars = append(chars, string(r))
+	}
+	push(vm, types.NewArray(chars))
+	return 1
+}
+
+// arg1: String
+// return: Array of code points of the string
+func builtinCodePoints(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	str, ok := pop(vm).(string)
+	vm.assert(ok)
+	codes := make([]interface{}, 0, len(str))
+	for _, r := range str {
+		codes = append(codes, int64(r))
+	}
+	push(vm, types.NewArray(codes))
+	return 1
+}
+
 func builtinAppend(argCnt int, vm *VM) (retCnt int) {
 	vm.assert(argCnt >= 2)
 	target := vm.curProto.stack.top(argCnt)
@@ -899,7 +1235,7 @@ func builtinLen(argCnt int, vm *VM) (retCnt int) {
 	case *types.Object:
 		length = int64(val.KVCount())
 	case string:
-		length = int64(len(val))
+		length = int64(vm.runes.len(val))
 	case *types.Buffer:
 		length = int64(len(val.Data))
 	default:
@@ -915,9 +1251,17 @@ func fprint(w io.Writer, val interface{}) {
 		fmt.Fprintf(w, "<closure>")
 	case *builtinFunc:
 		fmt.Fprintf(w, "<builtin:\"%s\">", val.name)
+	case *method:
+		fprint(w, val.fn)
 	case string:
 		fmt.Fprintf(w, "%s", val)
 	case *types.Object:
+		if val.IsError {
+			fprint(w, val.Get("kind"))
+			fmt.Fprintf(w, ": ")
+			fprint(w, val.Get("message"))
+			return
+		}
 		fmt.Fprintf(w, "Object{")
 		i := 0
 		cnt := val.KVCount()
@@ -944,6 +1288,12 @@ func fprint(w io.Writer, val interface{}) {
 		fmt.Fprintf(w, "<Buffer>")
 	case *types.File:
 		fmt.Fprintf(w, "<File>")
+	case *types.Time:
+		fmt.Fprintf(w, "<Time>")
+	case *types.Process:
+		fmt.Fprintf(w, "<Process>")
+	case *types.Regexp:
+		fmt.Fprintf(w, "<Regexp:\"%s\">", val.Regexp)
 	default:
 		fmt.Fprintf(w, "%v", val)
 	}
@@ -0,0 +1,106 @@
+package vm
+
+import (
+	"gscript/vm/types"
+	"strings"
+)
+
+// classes are constructors of user defined classes, or builtin Error
+func isErrorClass(v interface{}) bool {
+	f, ok := v.(*builtinFunc)
+	return ok && f.name == "Error"
+}
+
+func className(class *types.Closure) string {
+	return strings.TrimSuffix(class.Info.Name, ".__self")
+}
+
+// parent of class, nil if class extends nothing or extends Error
+func (vm *VM) parentOf(class *types.Closure) *types.Closure {
+	parent, _ := vm.parents[class.Info].(*types.Closure)
+	return parent
+}
+
+// call constructor of class. The object is under the arguments, and is left on the stack
+// when the constructor returns.
+func construct(vm *VM, class interface{}, argCnt uint32) {
+	if isErrorClass(class) {
+		// builtin Error initializes the object passed as first argument and returns it
+		callBuiltin(class.(*builtinFunc), vm, argCnt+1, 1)
+		return
+	}
+	callFunc(class.(*types.Closure), vm, argCnt, 0)
+}
+
+// pop the class, create an object of it below arguments and call the constructor
+func actionNewObject(vm *VM) {
+	argCnt := uint32(vm.curProto.frame.text[vm.curProto.frame.pc])
+	vm.curProto.frame.pc++
+
+	class := vm.curProto.stack.pop()
+	obj := types.NewObject()
+	if closure, ok := class.(*types.Closure); ok {
+		obj.Class = closure
+	} else if !isErrorClass(class) {
+		vm.exit("can not create an object of a %s", getType(class))
+	}
+	vm.curProto.stack.insert(int(argCnt), obj)
+	construct(vm, class, argCnt)
+}
+
+// pop the parent and the class, and make the class extend the parent
+func actionInherit(vm *VM) {
+	parent := vm.curProto.stack.pop()
+	class := vm.curProto.stack.pop().(*types.Closure)
+	switch p := parent.(type) {
+	case *types.Closure:
+		for ; p != nil; p = vm.parentOf(p) {
+			if p.Info == class.Info {
+				vm.exit("circular inheritance of class %s", className(class))
+			}
+		}
+	default:
+		if !isErrorClass(parent) {
+			vm.exit("class %s can not extend a %s", className(class), getType(parent))
+		}
+	}
+	vm.parents[class.Info] = parent
+}
+
+// pop the class and an Array of arguments, then call constructor of parent of the class
+// with the arguments. 'this' is under the Array.
+func actionCallSuper(vm *VM) {
+	class := vm.curProto.stack.pop().(*types.Closure)
+	args := vm.curProto.stack.pop().(*types.Array)
+	parent, ok := vm.parents[class.Info]
+	if !ok {
+		vm.exit("parent of class %s is not initialized", className(class))
+	}
+	for _, arg := range args.Data {
+		vm.curProto.stack.Push(arg)
+	}
+	construct(vm, parent, uint32(len(args.Data)))
+}
+
+// pop the class and replace the value with whether it is an object of the class or
+// subclasses of the class
+func actionInstanceOf(vm *VM) {
+	class := vm.curProto.stack.pop()
+	obj, isObj := vm.curProto.stack.Top().(*types.Object)
+	var result bool
+	if closure, ok := class.(*types.Closure); ok {
+		if isObj {
+			for c := obj.Class; c != nil; c = vm.parentOf(c) {
+				if c.Info == closure.Info {
+					result = true
+					break
+				}
+			}
+		}
+	} else if isErrorClass(class) {
+		result = isObj && obj.IsError
+	} else {
+		vm.exit("right operand of 'instanceof' should be a class, but got a %s", getType(class))
+	}
+	vm.curProto.stack.Replace(result)
+}
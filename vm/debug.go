@@ -111,12 +111,10 @@ func debugRun(vm *VM, args []string) {
 		if vm.stopped {
 			return
 		}
-		instruction := vm.curProto.frame.text[vm.curProto.frame.pc]
-		if instruction == proto.INS_STOP {
+		if vm.curProto.frame.text[vm.curProto.frame.pc] == proto.INS_STOP {
 			break
 		}
-		vm.curProto.frame.pc++
-		Execute(vm, instruction)
+		vm.step()
 	}
 }
 
@@ -163,9 +161,7 @@ func debugShowVar(vm *VM, args []string) {
 }
 
 func debugNext(vm *VM, args []string) {
-	instruction := vm.curProto.frame.text[vm.curProto.frame.pc]
-	vm.curProto.frame.pc++
-	Execute(vm, instruction)
+	vm.step()
 }
 
 func debugHelp(vm *VM, args []string) {
@@ -173,11 +169,12 @@ func debugHelp(vm *VM, args []string) {
 	fmt.Println()
 }
 
-func (vm *VM) Debug() {
+func (vm *VM) Debug() (err error) {
+	defer vm.recoverError(&err)
 	for {
 		if vm.stopped {
 			fmt.Println("done!")
-			break
+			return nil
 		}
 		bufr := bufio.NewReader(os.Stdin)
 
@@ -412,6 +409,42 @@ func showInstruction(vm *VM, text []byte, pc uint32) uint32 {
 		skip += 4
 	case proto.INS_END_TRY:
 		fmt.Printf("END_TRY")
+	case proto.INS_CALL_FINALLY:
+		pc++
+		fmt.Printf("CALL_FINALLY %d", getOpNum(text, pc))
+		skip += 4
+	case proto.INS_END_FINALLY:
+		fmt.Printf("END_FINALLY")
+	case proto.INS_LOAD_HOST:
+		pc++
+		fmt.Printf("LOAD_HOST %d", getOpNum(text, pc))
+		skip += 4
+	case proto.INS_ITER_NEW:
+		fmt.Printf("ITER_NEW")
+	case proto.INS_ITER_NEXT:
+		pc++
+		fmt.Printf("ITER_NEXT %d", getOpNum(text, pc))
+		skip += 4
+	case proto.INS_ITER_UNPACK:
+		pc++
+		idx := getOpNum(text, pc)
+		pc += 4
+		fmt.Printf("ITER_UNPACK %d %d", idx, getOpNum(text, pc))
+		skip += 8
+	case proto.INS_NEW_OBJECT:
+		pc++
+		fmt.Printf("NEW_OBJECT %d", text[pc])
+		skip++
+	case proto.INS_INHERIT:
+		fmt.Printf("INHERIT")
+	case proto.INS_CALL_SUPER:
+		fmt.Printf("CALL_SUPER")
+	case proto.INS_INSTANCEOF:
+		fmt.Printf("INSTANCEOF")
+	case proto.INS_CONCAT:
+		pc++
+		fmt.Printf("CONCAT %d", getOpNum(text, pc))
+		skip += 4
 	}
 	fmt.Println()
 	return uint32(skip)
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"gscript/vm/types"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp/syntax"
+	"strconv"
+	"syscall"
+)
+
+// Error object: {message, kind, errno, builtin}, errno and builtin are only set for errors raised by builtins
+func newError(obj *types.Object, message, kind string) *types.Object {
+	obj.IsError = true
+	obj.Set("message", message)
+	obj.Set("kind", kind)
+	return obj
+}
+
+func newBuiltinError(err error, builtin string) *types.Object {
+	obj := newError(types.NewObject(), err.Error(), errorKind(err))
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		obj.Set("errno", int64(errno))
+	}
+	obj.Set("builtin", builtin)
+	return obj
+}
+
+// builtins wrap it to report arguments out of range, e.g. a negative count
+var errInvalidArg = errors.New("invalid argument")
+
+func errorKind(err error) string {
+	var exitErr *exec.ExitError
+	var jsonErr jsonError
+	var timeErr timeError
+	var syntaxErr *syntax.Error
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, exec.ErrNotFound):
+		return "NotExist"
+	case errors.Is(err, fs.ErrExist):
+		return "Exist"
+	case errors.Is(err, fs.ErrPermission):
+		return "Permission"
+	case errors.Is(err, fs.ErrClosed):
+		return "Closed"
+	case errors.Is(err, fs.ErrInvalid):
+		return "Invalid"
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "EOF"
+	case errors.As(err, &exitErr):
+		return "Exit"
+	case errors.Is(err, errOverflow):
+		return "Overflow"
+	case errors.Is(err, strconv.ErrSyntax), errors.Is(err, strconv.ErrRange), errors.As(err, &jsonErr),
+		errors.Is(err, errNaN), errors.Is(err, errInvalidArg), errors.As(err, &timeErr),
+		errors.As(err, &syntaxErr), errors.Is(err, filepath.ErrBadPattern):
+		return "Invalid"
+	}
+	return "Error"
+}
+
+// Error([this], [message], [kind]), if this is given, initialize it as an Error object,
+// so that constructor of a class can make its instances errors.
+func builtinError(argCnt int, vm *VM) int {
+	args := make([]interface{}, argCnt)
+	for i := argCnt - 1; i >= 0; i-- {
+		args[i] = pop(vm)
+	}
+	obj, ok := types.NewObject(), false
+	if len(args) > 0 {
+		if obj, ok = args[0].(*types.Object); ok {
+			args = args[1:]
+		} else {
+			obj = types.NewObject()
+		}
+	}
+	vm.assert(len(args) <= 2)
+	message, kind := "", "Error"
+	if len(args) > 0 {
+		message, ok = args[0].(string)
+		vm.assert(ok)
+	}
+	if len(args) > 1 {
+		kind, ok = args[1].(string)
+		vm.assert(ok)
+	}
+	push(vm, newError(obj, message, kind))
+	return 1
+}
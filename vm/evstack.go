@@ -46,3 +46,11 @@ func (s *evalStack) pop() (val interface{}) {
 func (s *evalStack) Push(v interface{}) {
 	s.Buf = append(s.Buf, v)
 }
+
+// insert v below the top n values
+func (s *evalStack) insert(n int, v interface{}) {
+	pos := len(s.Buf) - n
+	s.Buf = append(s.Buf, nil)
+	copy(s.Buf[pos+1:], s.Buf[pos:])
+	s.Buf[pos] = v
+}
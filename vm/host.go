@@ -0,0 +1,39 @@
+package vm
+
+import "io"
+
+// HostFunc is a Go function provided by host application. Error returned by it is thrown
+// as an Error object in script.
+type HostFunc func(args []interface{}) (rets []interface{}, err error)
+
+// NewHostFunc wraps fn as a function value of script, name is used in error messages
+func NewHostFunc(name string, fn HostFunc) interface{} {
+	return &builtinFunc{
+		name: name,
+		handler: func(argCnt int, vm *VM) int {
+			args := make([]interface{}, argCnt)
+			for i := argCnt - 1; i >= 0; i-- {
+				args[i] = pop(vm)
+			}
+			rets, err := fn(args)
+			if err != nil {
+				throw(err, vm)
+				return 0
+			}
+			for _, ret := range rets {
+				push(vm, ret)
+			}
+			return len(rets)
+		},
+	}
+}
+
+// TypeOf returns type name of script value v, the same as builtin function type
+func TypeOf(v interface{}) string {
+	return getType(v)
+}
+
+// Fprint writes v to w in the same format as builtin function print
+func Fprint(w io.Writer, v interface{}) {
+	fprint(w, v)
+}
@@ -5,78 +5,96 @@ import (
 	"gscript/proto"
 	"gscript/vm/types"
 	"strconv"
+	"strings"
 )
 
-var actions = []func(vm *VM){
-	actionUnaryNOT,
-	actionUnaryNEG,
-	actionUnaryLNOT,
-	actionBinaryADD,
-	actionBinarySUB,
-	actionBinaryMUL,
-	actionBinaryDIV,
-	actionBinaryMOD,
-	actionBinaryAND,
-	actionBinaryXOR,
-	actionBinaryOR,
-	actionBinaryIDIV,
-	actionBinarySHR,
-	actionBinarySHL,
-	actionBinaryLE,
-	actionBinaryGE,
-	actionBinaryLT,
-	actionBinaryGT,
-	actionBinaryEQ,
-	actionBinaryNE,
-	actionBinaryLAND,
-	actionBinaryLOR,
-	actionBinaryATTR,
-	actionLoadNil,
-	actionStoreKV,
-	actionPushNameNil,
-	actionPushName,
-	actionCopyStackTop,
-	actionPopTop,
-	actionStop,
-	actionAttrAssign,
-	actionAttrAssignAddEq,
-	actionAttrAssignSubEq,
-	actionAttrAssignMulEq,
-	actionAttrAssignDivEq,
-	actionAttrAssignModEq,
-	actionAttrAssignAndEq,
-	actionAttrAssignXorEq,
-	actionAttrAssignOrEq,
-	actionAttrAccess,
-	actionRotTwo,
-	actionExport,
-	actionEndTry,
-	actionNewEmptyMap,
-	actionLoadConst,
-	actionLoadStdConst,
-	actionLoadName,
-	actionLoadFunc,
-	actionLoadStdFunc,
-	actionLoadBuiltin,
-	actionLoadAnonymous,
-	actionLoadStdAnonymous,
-	actionLoadUpValue,
-	actionLoadProto,
-	actionLoadStdlib,
-	actionStoreName,
-	actionStoreUpValue,
-	actionResizeNameTable,
-	actionSliceNew,
-	actionNewMap,
-	actionJumpRel,
-	actionJumpAbs,
-	actionJumpIf,
-	actionJumpLAnd,
-	actionJumpLOr,
-	actionJumpCase,
-	actionCall,
-	actionReturn,
-	actionTry,
+// actions are indexed by instructions, they are initialized in init to break the initialization
+// cycle, as builtin functions calling callbacks execute instructions too
+var actions []func(vm *VM)
+
+func init() {
+	actions = []func(vm *VM){
+		actionUnaryNOT,
+		actionUnaryNEG,
+		actionUnaryLNOT,
+		actionBinaryADD,
+		actionBinarySUB,
+		actionBinaryMUL,
+		actionBinaryDIV,
+		actionBinaryMOD,
+		actionBinaryAND,
+		actionBinaryXOR,
+		actionBinaryOR,
+		actionBinaryIDIV,
+		actionBinarySHR,
+		actionBinarySHL,
+		actionBinaryLE,
+		actionBinaryGE,
+		actionBinaryLT,
+		actionBinaryGT,
+		actionBinaryEQ,
+		actionBinaryNE,
+		actionBinaryLAND,
+		actionBinaryLOR,
+		actionBinaryATTR,
+		actionLoadNil,
+		actionStoreKV,
+		actionPushNameNil,
+		actionPushName,
+		actionCopyStackTop,
+		actionPopTop,
+		actionStop,
+		actionAttrAssign,
+		actionAttrAssignAddEq,
+		actionAttrAssignSubEq,
+		actionAttrAssignMulEq,
+		actionAttrAssignDivEq,
+		actionAttrAssignModEq,
+		actionAttrAssignAndEq,
+		actionAttrAssignXorEq,
+		actionAttrAssignOrEq,
+		actionAttrAccess,
+		actionRotTwo,
+		actionExport,
+		actionEndTry,
+		actionNewEmptyMap,
+		actionLoadConst,
+		actionLoadStdConst,
+		actionLoadName,
+		actionLoadFunc,
+		actionLoadStdFunc,
+		actionLoadBuiltin,
+		actionLoadAnonymous,
+		actionLoadStdAnonymous,
+		actionLoadUpValue,
+		actionLoadProto,
+		actionLoadStdlib,
+		actionStoreName,
+		actionStoreUpValue,
+		actionResizeNameTable,
+		actionSliceNew,
+		actionNewMap,
+		actionJumpRel,
+		actionJumpAbs,
+		actionJumpIf,
+		actionJumpLAnd,
+		actionJumpLOr,
+		actionJumpCase,
+		actionCall,
+		actionReturn,
+		actionTry,
+		actionCallFinally,
+		actionEndFinally,
+		actionLoadHost,
+		actionIterNew,
+		actionIterNext,
+		actionIterUnpack,
+		actionNewObject,
+		actionInherit,
+		actionCallSuper,
+		actionInstanceOf,
+		actionConcat,
+	}
 }
 
 func actionUnaryNOT(vm *VM) {
@@ -189,39 +207,49 @@ func actionBinaryLOR(vm *VM) {
 }
 
 func actionBinaryATTR(vm *VM) {
-	key := vm.curProto.stack.Top()
-	vm.curProto.stack.Pop()
+	key := vm.curProto.stack.pop()
 	obj := vm.curProto.stack.Top()
+	vm.curProto.stack.Replace(getAttr(vm, obj, key))
+}
+
+// obj[key] or obj.key, methods of primitive values are accessed by names
+func getAttr(vm *VM, obj, key interface{}) interface{} {
+	if name, ok := key.(string); ok && methods[getType(obj)] != nil {
+		m, ok := getMethod(obj, name)
+		if !ok {
+			vm.exit("%s has no method '%s'", getType(obj), name)
+		}
+		return m
+	}
 	if arr, ok := obj.(*types.Array); ok {
 		var idx int64
 		if idx, ok = key.(int64); !ok {
 			vm.exit("array index should be integer")
 		}
-		if idx > int64(len(arr.Data)) {
+		if idx < 0 || idx >= int64(len(arr.Data)) {
 			vm.exit("index out of range")
 		}
-		vm.curProto.stack.Replace(arr.Data[idx])
-		return
+		return arr.Data[idx]
 	}
 	if str, ok := obj.(string); ok {
 		var idx int64
 		if idx, ok = key.(int64); !ok {
-			vm.exit("array index should be integer")
+			vm.exit("string index should be integer")
 		}
-		if idx > int64(len(str)) {
+		r, ok := vm.runes.runeAt(str, idx)
+		if !ok {
 			vm.exit("index out of range")
 		}
-		vm.curProto.stack.Replace(int64(str[idx]))
-		return
+		return int64(r)
 	}
 	if obj, ok := obj.(*types.Object); ok {
 		if key == nil {
 			vm.exit("map key should not be nil")
 		}
-		vm.curProto.stack.Replace(obj.Get(key))
-		return
+		return obj.Get(key)
 	}
 	vm.exit("do not support attr access for %T", obj)
+	return nil
 }
 
 func actionLoadNil(vm *VM) {
@@ -374,20 +402,31 @@ func actionSliceNew(vm *VM) {
 	vm.curProto.stack.Push(types.NewArray(arr))
 }
 
+// concatenate parts of template string, values are formatted as print does
+func actionConcat(vm *VM) {
+	cnt := int(vm.getOpNum())
+	var b strings.Builder
+	for i := cnt; i > 0; i-- {
+		fprint(&b, vm.curProto.stack.top(i))
+	}
+	vm.curProto.stack.popN(cnt)
+	vm.curProto.stack.Push(b.String())
+}
+
 func actionNewMap(vm *VM) {
 	cnt := vm.getOpNum()
 	obj := types.NewObjectN(int(cnt))
-	for i := 0; i < int(cnt); i++ {
-		val := vm.curProto.stack.Top()
-		vm.curProto.stack.Pop()
-		key := vm.curProto.stack.Top()
-		vm.curProto.stack.Pop()
+	stack := vm.curProto.stack
+	// set keys in the order they are written
+	for i := 2 * int(cnt); i > 0; i -= 2 {
+		key, val := stack.top(i), stack.top(i-1)
 		if key == nil {
 			vm.exit("map key should not be nil")
 		}
 		obj.Set(key, val)
 	}
-	vm.curProto.stack.Push(obj)
+	stack.popN(2 * int(cnt))
+	stack.Push(obj)
 }
 
 func actionNewEmptyMap(vm *VM) {
@@ -462,39 +501,9 @@ func attrAssign(vm *VM, cb func(ori, val interface{}) interface{}) {
 }
 
 func actionAttrAccess(vm *VM) {
-	obj := vm.curProto.stack.Top()
-	vm.curProto.stack.Pop()
+	obj := vm.curProto.stack.pop()
 	key := vm.curProto.stack.Top()
-	if arr, ok := obj.(*types.Array); ok {
-		var idx int64
-		if idx, ok = key.(int64); !ok {
-			vm.exit("array index should be integer")
-		}
-		if idx > int64(len(arr.Data)) {
-			vm.exit("index out of range")
-		}
-		vm.curProto.stack.Replace(arr.Data[idx])
-		return
-	}
-	if str, ok := obj.(string); ok {
-		var idx int64
-		if idx, ok = key.(int64); !ok {
-			vm.exit("array index should be integer")
-		}
-		if idx > int64(len(str)) {
-			vm.exit("index out of range")
-		}
-		vm.curProto.stack.Replace(int64(str[idx]))
-		return
-	}
-	if obj, ok := obj.(*types.Object); ok {
-		if key == nil {
-			vm.exit("map key should not be nil")
-		}
-		vm.curProto.stack.Replace(obj.Get(key))
-		return
-	}
-	vm.exit("do not support attr access for %T", obj)
+	vm.curProto.stack.Replace(getAttr(vm, obj, key))
 }
 
 func actionJumpRel(vm *VM) {
@@ -548,13 +557,21 @@ func actionJumpCase(vm *VM) {
 }
 
 func callFunc(closure *types.Closure, vm *VM, argCnt uint32, wantRtnCnt int) {
+	depth := vm.curProto.frame.depth + 1
+	if vm.limits.MaxCallDepth > 0 && depth > vm.limits.MaxCallDepth {
+		vm.limitExceeded(ErrCallDepthLimit)
+	}
 	// generate a new function call frame
 	frame := &stackFrame{
+		depth:       depth,
 		pc:          0,
 		prev:        vm.curProto.frame,
 		symbolTable: newSymbolTable(),
 		wantRetCnt:  wantRtnCnt,
 		text:        closure.Info.Text,
+		lineTable:   closure.Info.LineTable,
+		filepath:    closure.Info.FilePath,
+		name:        closure.Info.Name,
 		upValues:    closure.UpValues,
 	}
 	vm.curProto.frame = frame
@@ -594,7 +611,8 @@ func callBuiltin(_func *builtinFunc, vm *VM, argCnt uint32, wantRtnCnt int) {
 		vm.builtinFuncFailed = false
 		return
 	}
-	for wantRtnCnt < realRtnCnt {
+	// negative wantRtnCnt means keeping all return values, see VM.CallValue
+	for wantRtnCnt >= 0 && wantRtnCnt < realRtnCnt {
 		vm.curProto.stack.Pop()
 		wantRtnCnt++
 	}
@@ -610,6 +628,10 @@ func call(_func interface{}, vm *VM, argCnt uint32, wantRtnCnt int) {
 		callFunc(_func, vm, argCnt, wantRtnCnt)
 	case *builtinFunc:
 		callBuiltin(_func, vm, argCnt, wantRtnCnt)
+	case *method:
+		// the bound value is passed as the first argument
+		vm.curProto.stack.insert(int(argCnt), _func.this)
+		callBuiltin(_func.fn, vm, argCnt+1, wantRtnCnt)
 	default:
 		vm.exit("top of stack is not a function, but a %s", getType(_func))
 	}
@@ -630,7 +652,8 @@ func actionReturn(vm *VM) {
 	realRtnCnt := int(vm.getOpNum())
 	wantRtnCnt := vm.curProto.frame.wantRetCnt
 
-	for wantRtnCnt < realRtnCnt {
+	// negative wantRtnCnt means keeping all return values, see VM.Call
+	for wantRtnCnt >= 0 && wantRtnCnt < realRtnCnt {
 		vm.curProto.stack.Pop()
 		wantRtnCnt++
 	}
@@ -664,13 +687,29 @@ func actionExport(vm *VM) {
 func actionTry(vm *VM) {
 	steps := vm.getOpNum()
 	addr := vm.curProto.frame.pc + steps
-	vm.curProto.frame.pushTryInfo(addr, uint32(len(vm.curProto.frame.symbolTable.values)))
+	frame := vm.curProto.frame
+	frame.pushTryInfo(addr, uint32(len(frame.symbolTable.values)), len(vm.curProto.stack.Buf))
 }
 
 func actionEndTry(vm *VM) {
 	vm.curProto.frame.popTryInfo()
 }
 
+// push return address and jump to finally block
+func actionCallFinally(vm *VM) {
+	steps := vm.getOpNum()
+	vm.curProto.stack.Push(vm.curProto.frame.pc)
+	vm.curProto.frame.pc += steps
+}
+
+func actionEndFinally(vm *VM) {
+	vm.curProto.frame.pc = vm.curProto.stack.pop().(uint32)
+}
+
+func actionLoadHost(vm *VM) {
+	vm.curProto.stack.Push(vm.hostValues[vm.getOpNum()])
+}
+
 func Execute(vm *VM, ins byte) {
 	actions[ins](vm)
 }
@@ -956,7 +995,7 @@ func andInt(a, b int64) interface{} {
 }
 
 func orInt(a, b int64) interface{} {
-	return a != 0 || b != 0
+	return a | b
 }
 
 func xorInt(a, b int64) interface{} {
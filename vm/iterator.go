@@ -0,0 +1,147 @@
+package vm
+
+import (
+	"gscript/vm/types"
+	"unicode/utf8"
+)
+
+// iterator of loop statement, it is stored in a hidden variable of the loop
+type iterator struct {
+	next  func() (key, val interface{}, ok bool) // for builtin iterables
+	user  *types.Object                          // iterator returned by __iter of user defined iterable
+	count int64                                  // count of iterations, default key of user defined iterator
+}
+
+// iterator of builtin iterables, nil if val is not iterable
+func newIterator(val interface{}) *iterator {
+	var i int
+	switch val := val.(type) {
+	case *types.Array:
+		return &iterator{next: func() (interface{}, interface{}, bool) {
+			if i >= len(val.Data) {
+				return nil, nil, false
+			}
+			i++
+			return int64(i - 1), val.Data[i-1], true
+		}}
+	case *types.Buffer:
+		return bytesIterator(val.Data)
+	case string:
+		// key is index of the character, i is byte offset of it
+		var idx int64
+		return &iterator{next: func() (interface{}, interface{}, bool) {
+			if i >= len(val) {
+				return nil, nil, false
+			}
+			r, size := utf8.DecodeRuneInString(val[i:])
+			i += size
+			idx++
+			return idx - 1, int64(r), true
+		}}
+	case *types.Object:
+		// instances of class Buffer are iterated by byte
+		if data, ok := bufferOf(val); ok {
+			return bytesIterator(data)
+		}
+		// modifying the object in loop does not affect the iteration
+		var kvs []types.KV
+		val.ForEach(func(k, v interface{}) {
+			kvs = append(kvs, types.KV{Key: k, Val: v})
+		})
+		return &iterator{next: func() (interface{}, interface{}, bool) {
+			if i >= len(kvs) {
+				return nil, nil, false
+			}
+			i++
+			return kvs[i-1].Key, kvs[i-1].Val, true
+		}}
+	}
+	return nil
+}
+
+// keys are indexes and values are bytes of data
+func bytesIterator(data []byte) *iterator {
+	var i int
+	return &iterator{next: func() (interface{}, interface{}, bool) {
+		if i >= len(data) {
+			return nil, nil, false
+		}
+		i++
+		return int64(i - 1), int64(data[i-1]), true
+	}}
+}
+
+// pop the iterable and push its iterator. If the iterable is an Object with method __iter,
+// the iterator is returned by __iter.
+func actionIterNew(vm *VM) {
+	val := vm.curProto.stack.pop()
+	if obj, ok := val.(*types.Object); ok {
+		if __iter := obj.Get("__iter"); __iter != nil {
+			call(__iter, vm, 0, 1)
+			return
+		}
+	}
+	it := newIterator(val)
+	if it == nil {
+		vm.exit("can not iterate a %s", getType(val))
+	}
+	vm.curProto.stack.Push(it)
+}
+
+// call method next of user defined iterator stored in variable, the result is pushed
+// when next returns. It does nothing for builtin iterables.
+func actionIterNext(vm *VM) {
+	idx := vm.getOpNum()
+	symbolTable := vm.curProto.frame.symbolTable
+	val := symbolTable.getValue(idx)
+	it, ok := val.(*iterator)
+	if !ok {
+		// the first iteration, val is returned by __iter
+		obj, ok := val.(*types.Object)
+		if !ok {
+			vm.exit("__iter should return an Object, but got a %s", getType(val))
+		}
+		it = &iterator{user: obj}
+		symbolTable.setValue(idx, it)
+	}
+	if it.user == nil {
+		return
+	}
+	next := it.user.Get("next")
+	if next == nil {
+		vm.exit("iterator returned by __iter has no method next")
+	}
+	call(next, vm, 0, 1)
+}
+
+// push key and value of the next element, or jump to the end of loop if iteration ends.
+// Method next of user defined iterator returns an Object with fields done, value and
+// optional key, key defaults to count of iterations.
+func actionIterUnpack(vm *VM) {
+	it := vm.curProto.frame.symbolTable.getValue(vm.getOpNum()).(*iterator)
+	steps := vm.getOpNum()
+	var key, val interface{}
+	if it.user == nil {
+		var ok bool
+		if key, val, ok = it.next(); !ok {
+			vm.curProto.frame.pc += steps
+			return
+		}
+	} else {
+		ret := vm.curProto.stack.pop()
+		result, ok := ret.(*types.Object)
+		if !ok {
+			vm.exit("next of iterator should return an Object, but got a %s", getType(ret))
+		}
+		if getBool(result.Get("done")) {
+			vm.curProto.frame.pc += steps
+			return
+		}
+		if key, val = result.Get("key"), result.Get("value"); key == nil {
+			key = it.count
+		}
+		it.count++
+	}
+	vm.curProto.stack.Push(key)
+	vm.curProto.stack.Push(val)
+}
@@ -0,0 +1,281 @@
+package vm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gscript/vm/types"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// builtin functions of std library json
+
+// error of parsing or stringifying json, its kind is Invalid
+type jsonError string
+
+func (e jsonError) Error() string {
+	return string(e)
+}
+
+// arg1: String
+// return: value of the json, numbers without fraction and exponent are parsed as integers
+func builtinJSONParse(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	dec := json.NewDecoder(strings.NewReader(stringArg(pop(vm), vm)))
+	dec.UseNumber()
+	val, err := parseJSON(dec)
+	if err == nil {
+		if _, e := dec.Token(); e != io.EOF {
+			err = jsonError(fmt.Sprintf("invalid character after top-level value at offset %d", dec.InputOffset()))
+		}
+	}
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, val)
+	return 1
+}
+
+func parseJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, jsonParseError(err, dec)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			data := make([]interface{}, 0)
+			for dec.More() {
+				val, err := parseJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				data = append(data, val)
+			}
+			if _, err = dec.Token(); err != nil {
+				return nil, jsonParseError(err, dec)
+			}
+			return types.NewArray(data), nil
+		}
+		// keys of the object are kept in order
+		obj := types.NewObject()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, jsonParseError(err, dec)
+			}
+			val, err := parseJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, val)
+		}
+		if _, err = dec.Token(); err != nil {
+			return nil, jsonParseError(err, dec)
+		}
+		return obj, nil
+	case json.Number:
+		if num, err := tok.Int64(); err == nil {
+			return num, nil
+		}
+		num, err := tok.Float64()
+		if err != nil {
+			return nil, jsonError(fmt.Sprintf("number %s out of range", tok))
+		}
+		return num, nil
+	}
+	return tok, nil
+}
+
+func jsonParseError(err error, dec *json.Decoder) error {
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return jsonError(fmt.Sprintf("%s at offset %d", err, syntaxErr.Offset))
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+		return jsonError("unexpected end of JSON input")
+	}
+	return jsonError(fmt.Sprintf("%s at offset %d", err, dec.InputOffset()))
+}
+
+// arg1: value, arg2: indent, Integer for count of spaces or String, default nil means no indent
+// return: String
+func builtinJSONStringify(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1 || argCnt == 2)
+	args := popArgs(argCnt, vm)
+	var indent string
+	if argCnt == 2 {
+		switch v := args[1].(type) {
+		case int64:
+			if v < 0 || v > 10 {
+				throw(fmt.Errorf("indent %d is not between 0 and 10: %w", v, errInvalidArg), vm)
+				return 0
+			}
+			indent = strings.Repeat(" ", int(v))
+		case string:
+			indent = v
+		case nil:
+		default:
+			vm.assert(false)
+		}
+	}
+	e := &jsonEncoder{indent: indent, visiting: map[interface{}]bool{}}
+	if err := e.encode(args[0], 0); err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, e.b.String())
+	return 1
+}
+
+type jsonEncoder struct {
+	b        strings.Builder
+	indent   string
+	visiting map[interface{}]bool // arrays and objects being encoded, to detect cycles
+}
+
+// fields of functions in objects are omitted, and functions in arrays are encoded as null
+func (e *jsonEncoder) encode(val interface{}, depth int) error {
+	switch val := val.(type) {
+	case nil:
+		e.b.WriteString("null")
+	case bool:
+		e.b.WriteString(strconv.FormatBool(val))
+	case int64:
+		e.b.WriteString(strconv.FormatInt(val, 10))
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) {
+			return jsonError(fmt.Sprintf("unsupported number %v", val))
+		}
+		data, _ := json.Marshal(val)
+		e.b.Write(data)
+		// keep floats without fraction as floats when they are parsed back
+		if !strings.ContainsAny(string(data), ".eE") {
+			e.b.WriteString(".0")
+		}
+	case string:
+		writeJSONString(&e.b, val)
+	case *types.Buffer:
+		writeJSONString(&e.b, base64.StdEncoding.EncodeToString(val.Data))
+	case *types.Array:
+		if e.visiting[val] {
+			return jsonError("can not stringify cyclic structure")
+		}
+		e.visiting[val] = true
+		defer delete(e.visiting, val)
+		e.b.WriteByte('[')
+		for i, v := range val.Data {
+			if i > 0 {
+				e.b.WriteByte(',')
+			}
+			e.newline(depth + 1)
+			if callable(v) {
+				v = nil
+			}
+			if err := e.encode(v, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(val.Data) > 0 {
+			e.newline(depth)
+		}
+		e.b.WriteByte(']')
+	case *types.Object:
+		if buf, ok := bufferOf(val); ok {
+			writeJSONString(&e.b, base64.StdEncoding.EncodeToString(buf))
+			break
+		}
+		if e.visiting[val] {
+			return jsonError("can not stringify cyclic structure")
+		}
+		e.visiting[val] = true
+		defer delete(e.visiting, val)
+		e.b.WriteByte('{')
+		cnt := 0
+		var err error
+		val.ForEach(func(k, v interface{}) {
+			if err != nil || callable(v) {
+				return
+			}
+			if cnt > 0 {
+				e.b.WriteByte(',')
+			}
+			cnt++
+			e.newline(depth + 1)
+			var key strings.Builder
+			fprint(&key, k)
+			writeJSONString(&e.b, key.String())
+			e.b.WriteByte(':')
+			if e.indent != "" {
+				e.b.WriteByte(' ')
+			}
+			err = e.encode(v, depth+1)
+		})
+		if err != nil {
+			return err
+		}
+		if cnt > 0 {
+			e.newline(depth)
+		}
+		e.b.WriteByte('}')
+	default:
+		return jsonError(fmt.Sprintf("can not stringify a %s", getType(val)))
+	}
+	return nil
+}
+
+// bytes of an instance of class Buffer of std library Buffer
+func bufferOf(obj *types.Object) ([]byte, bool) {
+	if obj.Class == nil {
+		return nil, false
+	}
+	buf, ok := obj.Get("_buffer").(*types.Buffer)
+	if !ok {
+		return nil, false
+	}
+	if cap, ok := obj.Get("_cap").(int64); ok && cap >= 0 && int(cap) < len(buf.Data) {
+		return buf.Data[:cap], true
+	}
+	return buf.Data, true
+}
+
+func (e *jsonEncoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.b.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.b.WriteString(e.indent)
+	}
+}
+
+// quote str as a json string, invalid UTF-8 bytes are replaced with U+FFFD
+func writeJSONString(b *strings.Builder, str string) {
+	b.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
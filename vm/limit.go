@@ -0,0 +1,61 @@
+package vm
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInstructionLimit = errors.New("instruction limit exceeded")
+	ErrCallDepthLimit   = errors.New("call depth limit exceeded")
+	ErrStackLimit       = errors.New("stack size limit exceeded")
+)
+
+// Limits restricts resources used by script, zero value of a field means unlimited.
+// Exceeding a limit stops the script with a *RuntimeError wrapping one of the errors above.
+type Limits struct {
+	MaxInstructions uint64 // max count of instructions executed by Run or Call
+	MaxCallDepth    int    // max depth of nested function calls, calls through callbacks are limited anyway
+	MaxStackSize    int    // max count of values in evaluation stack
+}
+
+// check context every contextCheckInterval instructions, it is a mask of instruction count
+const contextCheckInterval = 1<<10 - 1
+
+func (vm *VM) SetLimits(limits Limits) {
+	vm.limits = limits
+}
+
+// SetContext sets context of the following Run or Call, script stops with a *RuntimeError
+// wrapping ctx.Err() once ctx is done. Blocking builtin functions other than sleep
+// and waiting for processes are not interrupted.
+func (vm *VM) SetContext(ctx context.Context) {
+	vm.ctx = ctx
+}
+
+// execute the next instruction and check limits
+func (vm *VM) step() {
+	vm.steps++
+	if vm.limits.MaxInstructions > 0 && vm.steps > vm.limits.MaxInstructions {
+		vm.limitExceeded(ErrInstructionLimit)
+	}
+	if vm.steps&contextCheckInterval == 0 && vm.ctx != nil {
+		select {
+		case <-vm.ctx.Done():
+			vm.limitExceeded(vm.ctx.Err())
+		default:
+		}
+	}
+	if vm.limits.MaxStackSize > 0 && len(vm.curProto.stack.Buf) > vm.limits.MaxStackSize {
+		vm.limitExceeded(ErrStackLimit)
+	}
+	instruction := vm.curProto.frame.text[vm.curProto.frame.pc]
+	vm.curProto.frame.pc++
+	Execute(vm, instruction)
+}
+
+func (vm *VM) limitExceeded(err error) {
+	e := vm.newRuntimeError(err.Error())
+	e.Err = err
+	panic(e)
+}
@@ -0,0 +1,297 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// builtin functions of std library math, Integers are kept as Integers whenever the result is exact
+
+var (
+	errOverflow = errors.New("integer overflow")
+	errNaN      = errors.New("not a number")
+)
+
+func numberArg(arg interface{}, vm *VM) float64 {
+	num, ok := toFloat(arg)
+	vm.assert(ok)
+	return num
+}
+
+var floatFuncs = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"cbrt":  math.Cbrt,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"log2":  math.Log2,
+	"log10": math.Log10,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+	"sinh":  math.Sinh,
+	"cosh":  math.Cosh,
+	"tanh":  math.Tanh,
+}
+
+// arg1: Number, arg2: name of the function, such as "sqrt" and "sin"
+// return: Float
+func builtinMathFloat(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	fn, ok := floatFuncs[stringArg(args[1], vm)]
+	vm.assert(ok)
+	push(vm, fn(numberArg(args[0], vm)))
+	return 1
+}
+
+// arg1: Number, arg2: Number, arg3: "atan2" or "hypot"
+// return: Float
+func builtinMathFloat2(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 3)
+	args := popArgs(argCnt, vm)
+	x, y := numberArg(args[0], vm), numberArg(args[1], vm)
+	switch stringArg(args[2], vm) {
+	case "atan2":
+		push(vm, math.Atan2(x, y))
+	case "hypot":
+		push(vm, math.Hypot(x, y))
+	default:
+		vm.assert(false)
+	}
+	return 1
+}
+
+// arg1: Number
+// return: Number of the same type, throws an error of overflow for the minimum Integer
+func builtinMathAbs(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	switch num := pop(vm).(type) {
+	case int64:
+		if num == math.MinInt64 {
+			throw(errOverflow, vm)
+			return 0
+		}
+		if num < 0 {
+			num = -num
+		}
+		push(vm, num)
+	case float64:
+		push(vm, math.Abs(num))
+	default:
+		vm.assert(false)
+	}
+	return 1
+}
+
+// arg1: Number, arg2: "floor", "ceil", "round" or "trunc"
+// return: Integer
+func builtinMathRound(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	var round func(float64) float64
+	switch stringArg(args[1], vm) {
+	case "floor":
+		round = math.Floor
+	case "ceil":
+		round = math.Ceil
+	case "round":
+		round = math.Round
+	case "trunc":
+		round = math.Trunc
+	default:
+		vm.assert(false)
+	}
+	switch num := args[0].(type) {
+	case int64:
+		push(vm, num)
+	case float64:
+		res, err := floatToInt(round(num))
+		if err != nil {
+			throw(err, vm)
+			return 0
+		}
+		push(vm, res)
+	default:
+		vm.assert(false)
+	}
+	return 1
+}
+
+// num should have no fraction
+func floatToInt(num float64) (int64, error) {
+	if math.IsNaN(num) {
+		return 0, fmt.Errorf("can not convert NaN to Integer: %w", errNaN)
+	}
+	// float64(math.MaxInt64) is 2^63, which is out of range
+	if num < math.MinInt64 || num >= math.MaxInt64 {
+		return 0, fmt.Errorf("can not convert %v to Integer: %w", num, errOverflow)
+	}
+	return int64(num), nil
+}
+
+// arg1: Number, arg2: Number
+// return: Integer if both are Integers and the exponent is not negative, otherwise Float
+func builtinMathPow(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	base, ok1 := args[0].(int64)
+	exp, ok2 := args[1].(int64)
+	if !ok1 || !ok2 || exp < 0 {
+		push(vm, math.Pow(numberArg(args[0], vm), numberArg(args[1], vm)))
+		return 1
+	}
+	res, ok := powInt(base, exp)
+	if !ok {
+		throw(errOverflow, vm)
+		return 0
+	}
+	push(vm, res)
+	return 1
+}
+
+// exponentiation by squaring, false if the result overflows
+func powInt(base, exp int64) (int64, bool) {
+	res := int64(1)
+	for ; exp > 0; exp >>= 1 {
+		var ok bool
+		if exp&1 == 1 {
+			if res, ok = mulExact(res, base); !ok {
+				return 0, false
+			}
+		}
+		if exp > 1 {
+			if base, ok = mulExact(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return res, true
+}
+
+func addExact(a, b int64) (int64, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+func subExact(a, b int64) (int64, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+
+func mulExact(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	// MinInt64 / -1 is MinInt64 again
+	if c/b != a || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return c, true
+}
+
+// arg1: Integer, arg2: Integer, arg3: "add", "sub" or "mul"
+// return: Integer, throws an error of overflow instead of wrapping around
+func builtinMathExact(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 3)
+	args := popArgs(argCnt, vm)
+	a, ok1 := args[0].(int64)
+	b, ok2 := args[1].(int64)
+	vm.assert(ok1 && ok2)
+	var res int64
+	var ok bool
+	switch stringArg(args[2], vm) {
+	case "add":
+		res, ok = addExact(a, b)
+	case "sub":
+		res, ok = subExact(a, b)
+	case "mul":
+		res, ok = mulExact(a, b)
+	default:
+		vm.assert(false)
+	}
+	if !ok {
+		throw(errOverflow, vm)
+		return 0
+	}
+	push(vm, res)
+	return 1
+}
+
+// arg1...: Numbers, at least one
+// return: the minimum one, NaN if any of them is NaN
+func builtinMathMin(argCnt int, vm *VM) (retCnt int) {
+	push(vm, extremum(popArgs(argCnt, vm), vm, func(a, b float64) bool { return a < b }))
+	return 1
+}
+
+// arg1...: Numbers, at least one
+// return: the maximum one, NaN if any of them is NaN
+func builtinMathMax(argCnt int, vm *VM) (retCnt int) {
+	push(vm, extremum(popArgs(argCnt, vm), vm, func(a, b float64) bool { return a > b }))
+	return 1
+}
+
+func extremum(args []interface{}, vm *VM, better func(a, b float64) bool) interface{} {
+	vm.assert(len(args) > 0)
+	res, resNum := args[0], numberArg(args[0], vm)
+	for _, arg := range args[1:] {
+		num := numberArg(arg, vm)
+		if math.IsNaN(resNum) {
+			continue
+		}
+		if math.IsNaN(num) || better(num, resNum) {
+			res, resNum = arg, num
+		}
+	}
+	return res
+}
+
+// arg1: Number
+// return: Integer, the fraction of Float is discarded
+func builtinMathToInt(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	switch num := pop(vm).(type) {
+	case int64:
+		push(vm, num)
+	case float64:
+		res, err := floatToInt(math.Trunc(num))
+		if err != nil {
+			throw(err, vm)
+			return 0
+		}
+		push(vm, res)
+	default:
+		vm.assert(false)
+	}
+	return 1
+}
+
+// arg1: Number
+// return: Float
+func builtinMathToFloat(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, numberArg(pop(vm), vm))
+	return 1
+}
+
+// arg1: Number
+// return: Boolean
+func builtinMathIsNaN(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, math.IsNaN(numberArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: Number, arg2: sign, positive for +Inf, negative for -Inf and 0 for either
+// return: Boolean
+func builtinMathIsInf(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, math.IsInf(numberArg(args[0], vm), intArg(args[1], vm)))
+	return 1
+}
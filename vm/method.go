@@ -0,0 +1,163 @@
+package vm
+
+import (
+	"fmt"
+	"gscript/vm/types"
+	"strconv"
+)
+
+// method of primitive value, such as "abc".len. The value is bound to the method and passed
+// to the handler as the first argument when the method is called, so that values need not
+// be boxed into Objects.
+type method struct {
+	this interface{}
+	fn   *builtinFunc
+}
+
+// method tables of primitive types, indexed by type name returned by getType
+var methods = map[string]map[string]*builtinFunc{
+	"String": newMethodTable("String", map[string]func(int, *VM) int{
+		"len":        builtinLen,
+		"sub":        builtinSub,
+		"chars":      builtinChars,
+		"codePoints": builtinCodePoints,
+		"split":      builtinStringsSplit,
+		"trim":       trimMethod("both"),
+		"trimLeft":   trimMethod("left"),
+		"trimRight":  trimMethod("right"),
+		"trimPrefix": builtinStringsTrimPrefix,
+		"trimSuffix": builtinStringsTrimSuffix,
+		"replace":    builtinStringsReplace,
+		"contains":   builtinStringsContains,
+		"index":      builtinStringsIndex,
+		"lastIndex":  builtinStringsLastIndex,
+		"hasPrefix":  builtinStringsHasPrefix,
+		"hasSuffix":  builtinStringsHasSuffix,
+		"upper":      builtinStringsUpper,
+		"lower":      builtinStringsLower,
+		"repeat":     builtinStringsRepeat,
+		"padStart":   padMethod(true),
+		"padEnd":     padMethod(false),
+		"fields":     builtinStringsFields,
+	}),
+	"Array": newMethodTable("Array", map[string]func(int, *VM) int{
+		"len":      builtinLen,
+		"sub":      builtinSub,
+		"push":     builtinAppend,
+		"pop":      builtinArrayPop,
+		"join":     builtinStringsJoin,
+		"sort":     builtinArraySort,
+		"map":      builtinArrayMap,
+		"filter":   builtinArrayFilter,
+		"reduce":   builtinArrayReduce,
+		"find":     builtinArrayFind,
+		"indexOf":  builtinArrayIndexOf,
+		"reverse":  builtinArrayReverse,
+		"splice":   builtinArraySplice,
+		"insert":   builtinArrayInsert,
+		"removeAt": builtinArrayRemoveAt,
+		"concat":   builtinArrayConcat,
+		"flat":     builtinArrayFlat,
+	}),
+	"Regexp": newMethodTable("Regexp", map[string]func(int, *VM) int{
+		"source":    builtinRegexpSource,
+		"test":      builtinRegexpTest,
+		"find":      builtinRegexpFind,
+		"findAll":   builtinRegexpFindAll,
+		"findIndex": builtinRegexpFindIndex,
+		"match":     builtinRegexpMatch,
+		"matchAll":  builtinRegexpMatchAll,
+		"groups":    builtinRegexpGroups,
+		"replace":   builtinRegexpReplace,
+		"split":     builtinRegexpSplit,
+	}),
+	"Number": newMethodTable("Number", map[string]func(int, *VM) int{
+		"toString": builtinNumberToString,
+		"toFixed":  builtinStringsToFixed,
+	}),
+}
+
+func newMethodTable(typ string, handlers map[string]func(int, *VM) int) map[string]*builtinFunc {
+	table := make(map[string]*builtinFunc, len(handlers))
+	for name, handler := range handlers {
+		table[name] = &builtinFunc{handler: handler, name: typ + "." + name}
+	}
+	return table
+}
+
+// method of primitive value val bound to val, false if there is no such method
+func getMethod(val interface{}, name string) (*method, bool) {
+	fn, ok := methods[getType(val)][name]
+	if !ok {
+		return nil, false
+	}
+	return &method{this: val, fn: fn}, true
+}
+
+// arg1: String, arg2: cutset, white spaces are trimmed if cutset is omitted
+func trimMethod(side string) func(int, *VM) int {
+	return func(argCnt int, vm *VM) int {
+		vm.assert(argCnt == 1 || argCnt == 2)
+		if argCnt == 1 {
+			push(vm, nil)
+		}
+		push(vm, side)
+		return builtinStringsTrim(3, vm)
+	}
+}
+
+// arg1: String, arg2: length, arg3: pad string, default " "
+func padMethod(atStart bool) func(int, *VM) int {
+	return func(argCnt int, vm *VM) int {
+		vm.assert(argCnt == 2 || argCnt == 3)
+		if argCnt == 2 {
+			push(vm, " ")
+		}
+		push(vm, atStart)
+		return builtinStringsPad(4, vm)
+	}
+}
+
+// arg1: Array
+// return: the removed last element, nil if the array is empty
+func builtinArrayPop(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	arr, ok := pop(vm).(*types.Array)
+	vm.assert(ok)
+	if len(arr.Data) == 0 {
+		push(vm, nil)
+		return 1
+	}
+	last := arr.Data[len(arr.Data)-1]
+	arr.Data = arr.Data[:len(arr.Data)-1]
+	push(vm, last)
+	return 1
+}
+
+// arg1: Number, arg2: base between 2 and 36, default 10, only integers support bases other than 10
+// return: String
+func builtinNumberToString(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1 || argCnt == 2)
+	args := popArgs(argCnt, vm)
+	base := 10
+	if argCnt == 2 {
+		base = intArg(args[1], vm)
+	}
+	switch num := args[0].(type) {
+	case int64:
+		if base < 2 || base > 36 {
+			throw(fmt.Errorf("base %d is not between 2 and 36: %w", base, errInvalidArg), vm)
+			return 0
+		}
+		push(vm, strconv.FormatInt(num, base))
+	case float64:
+		if base != 10 {
+			throw(fmt.Errorf("base of Float should be 10, but got %d: %w", base, errInvalidArg), vm)
+			return 0
+		}
+		push(vm, fmt.Sprintf("%v", num))
+	default:
+		vm.assert(false)
+	}
+	return 1
+}
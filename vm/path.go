@@ -0,0 +1,109 @@
+package vm
+
+import (
+	"path/filepath"
+)
+
+// builtin functions of std library path, paths use separator of the operating system
+
+// arg1...: elements of path
+// return: String, elements joined by separator and cleaned, empty elements are ignored
+func builtinPathJoin(argCnt int, vm *VM) (retCnt int) {
+	args := popArgs(argCnt, vm)
+	elems := make([]string, argCnt)
+	for i, arg := range args {
+		elems[i] = stringArg(arg, vm)
+	}
+	push(vm, filepath.Join(elems...))
+	return 1
+}
+
+// arg1: path
+// return: the shortest path equivalent to arg1, "." if arg1 is empty
+func builtinPathClean(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, filepath.Clean(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: path
+// return: all but the last element of arg1
+func builtinPathDir(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, filepath.Dir(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: path
+// return: the last element of arg1, trailing separators are removed
+func builtinPathBase(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, filepath.Base(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: path
+// return: extension of file name including the dot, "" if there is no extension
+func builtinPathExt(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, filepath.Ext(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: path
+// return: absolute path of arg1 relative to the working directory
+func builtinPathAbs(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	path, err := filepath.Abs(stringArg(pop(vm), vm))
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, path)
+	return 1
+}
+
+// arg1: base path, arg2: target path
+// return: relative path which is equivalent to arg2 when joined to arg1
+func builtinPathRel(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	path, err := filepath.Rel(stringArg(args[0], vm), stringArg(args[1], vm))
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, path)
+	return 1
+}
+
+// arg1: path
+// return: [dir, file], dir ends with separator if it is not empty
+func builtinPathSplit(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	dir, file := filepath.Split(stringArg(pop(vm), vm))
+	push(vm, stringArray([]string{dir, file}))
+	return 1
+}
+
+// arg1: path
+// return: Boolean
+func builtinPathIsAbs(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, filepath.IsAbs(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: shell pattern such as "*.gs", arg2: name
+// return: whether the whole name matches the pattern
+func builtinPathMatch(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	matched, err := filepath.Match(stringArg(args[0], vm), stringArg(args[1], vm))
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, matched)
+	return 1
+}
@@ -0,0 +1,237 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"gscript/vm/types"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// builtin functions of class Process of std library os
+
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+func signalName(sig syscall.Signal) string {
+	for name, s := range signals {
+		if s == sig {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+func processArg(arg interface{}, vm *VM) *types.Process {
+	proc, ok := arg.(*types.Process)
+	vm.assert(ok)
+	return proc
+}
+
+// pipes of process, ends used by child are closed in parent once child starts
+type processPipes struct {
+	parent []*os.File
+	child  []*os.File
+}
+
+func (p *processPipes) pipe() (r, w *os.File, err error) {
+	if r, w, err = os.Pipe(); err == nil {
+		p.parent = append(p.parent, r, w)
+	}
+	return
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// arg1: command, arg2: Array<String> of arguments, arg3: working directory, nil means current one,
+// arg4: Object of environment variables added to those of current process, arg5: stdin, nil, "inherit",
+// "pipe", File or Buffer of input, arg6, arg7: stdout and stderr, "capture", "pipe", "inherit", "ignore"
+// or File
+// return: Process, pid, Files of parent's ends of stdin, stdout and stderr if they are "pipe", otherwise nil
+func builtinSpawn(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 7)
+	args := popArgs(argCnt, vm)
+	command := stringArg(args[0], vm)
+	arr := arrayArg(args[1], vm)
+	cmdArgs := make([]string, len(arr.Data))
+	for i := range arr.Data {
+		cmdArgs[i] = stringArg(arr.Data[i], vm)
+	}
+	if err := vm.sandbox.checkExec(command); err != nil {
+		throw(err, vm)
+		return 0
+	}
+	cmd := exec.Command(command, cmdArgs...)
+	if args[2] != nil {
+		cmd.Dir = stringArg(args[2], vm)
+	}
+	if args[3] != nil {
+		env, ok := args[3].(*types.Object)
+		vm.assert(ok)
+		// later ones take precedence over variables of the same keys
+		cmd.Env = os.Environ()
+		env.ForEach(func(k, v interface{}) {
+			cmd.Env = append(cmd.Env, stringArg(k, vm)+"="+stringArg(v, vm))
+		})
+	}
+	proc := types.NewProcess(cmd)
+	rets := []interface{}{proc, nil, nil, nil, nil}
+	var pipes processPipes
+	var err error
+	switch stdin := args[4].(type) {
+	case nil:
+	case *types.File:
+		cmd.Stdin = stdin.File
+	case *types.Buffer:
+		cmd.Stdin = bytes.NewReader(stdin.Data)
+	case string:
+		switch stdin {
+		case "inherit":
+			cmd.Stdin = os.Stdin
+		case "pipe":
+			var r, w *os.File
+			if r, w, err = pipes.pipe(); err == nil {
+				cmd.Stdin = r
+				pipes.child = append(pipes.child, r)
+				rets[2] = types.NewFile(w)
+			}
+		default:
+			vm.assert(false)
+		}
+	default:
+		vm.assert(false)
+	}
+	outputs := []struct {
+		mode    interface{}
+		std     *os.File
+		capture **bytes.Buffer
+		set     func(w io.Writer)
+	}{
+		{args[5], os.Stdout, &proc.Stdout, func(w io.Writer) { cmd.Stdout = w }},
+		{args[6], os.Stderr, &proc.Stderr, func(w io.Writer) { cmd.Stderr = w }},
+	}
+	for i, out := range outputs {
+		if err != nil {
+			break
+		}
+		switch mode := out.mode.(type) {
+		case *types.File:
+			out.set(mode.File)
+		case string:
+			switch mode {
+			case "capture":
+				*out.capture = &bytes.Buffer{}
+				out.set(*out.capture)
+			case "inherit":
+				out.set(out.std)
+			case "pipe":
+				var r, w *os.File
+				if r, w, err = pipes.pipe(); err == nil {
+					out.set(w)
+					pipes.child = append(pipes.child, w)
+					rets[3+i] = types.NewFile(r)
+				}
+			case "ignore":
+			default:
+				vm.assert(false)
+			}
+		default:
+			vm.assert(false)
+		}
+	}
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		closeFiles(pipes.parent)
+		throw(err, vm)
+		return 0
+	}
+	closeFiles(pipes.child)
+	go func() {
+		proc.Err = cmd.Wait()
+		close(proc.Done)
+	}()
+	rets[1] = int64(cmd.Process.Pid)
+	for _, ret := range rets {
+		push(vm, ret)
+	}
+	return len(rets)
+}
+
+// arg1: Process, arg2: timeout in milliseconds, negative means no timeout
+// return: false if the process does not exit before timeout, otherwise true, exit code, name of the
+// signal which terminates the process or nil, captured stdout and stderr or nil if they are not captured.
+// Exit code is -1 if the process is terminated by a signal. Waiting is interrupted once context of vm is done.
+func builtinProcessWait(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	proc := processArg(args[0], vm)
+	ms := numberArg(args[1], vm)
+	var timeout <-chan time.Time
+	if ms >= 0 {
+		timer := time.NewTimer(time.Duration(ms * float64(time.Millisecond)))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	// receiving from nil channel blocks forever
+	var ctxDone <-chan struct{}
+	if vm.ctx != nil {
+		ctxDone = vm.ctx.Done()
+	}
+	select {
+	case <-proc.Done:
+	case <-timeout:
+		push(vm, false)
+		return 1
+	case <-ctxDone:
+		vm.limitExceeded(vm.ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	if proc.Err != nil && !errors.As(proc.Err, &exitErr) {
+		throw(proc.Err, vm)
+		return 0
+	}
+	state := proc.Cmd.ProcessState
+	var signal interface{}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		signal = signalName(status.Signal())
+	}
+	push(vm, true)
+	push(vm, int64(state.ExitCode()))
+	push(vm, signal)
+	for _, out := range []*bytes.Buffer{proc.Stdout, proc.Stderr} {
+		if out == nil {
+			push(vm, nil)
+		} else {
+			push(vm, types.NewBuffer(out.Bytes()))
+		}
+	}
+	return 5
+}
+
+// arg1: Process, arg2: name of signal, such as "SIGTERM" and "SIGKILL"
+// nothing happens if the process has exited
+func builtinProcessKill(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	proc := processArg(args[0], vm)
+	sig, ok := signals[stringArg(args[1], vm)]
+	vm.assert(ok)
+	if err := proc.Cmd.Process.Signal(sig); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		throw(err, vm)
+	}
+	return 0
+}
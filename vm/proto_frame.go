@@ -15,6 +15,8 @@ type protoFrame struct {
 func newProtoFrame(_proto proto.Proto) *protoFrame {
 	topFrame := newFuncFrame()
 	topFrame.text = _proto.Text
+	topFrame.lineTable = _proto.LineTable
+	topFrame.filepath = _proto.FilePath
 	frame := &protoFrame{
 		topFrame:       topFrame,
 		frame:          topFrame,
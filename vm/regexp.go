@@ -0,0 +1,204 @@
+package vm
+
+import (
+	"gscript/vm/types"
+	"regexp"
+	"strings"
+)
+
+// builtin functions of std library regexp, they are also methods of Regexp
+
+func regexpArg(arg interface{}, vm *VM) *regexp.Regexp {
+	re, ok := arg.(*types.Regexp)
+	vm.assert(ok)
+	return re.Regexp
+}
+
+// arg1: pattern in syntax of Go's regexp (RE2)
+// return: Regexp, throws an error of kind Invalid if pattern is invalid
+func builtinRegexpCompile(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	re, err := regexp.Compile(stringArg(pop(vm), vm))
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, types.NewRegexp(re))
+	return 1
+}
+
+// arg1: String
+// return: String with all metacharacters escaped, which matches arg1 literally
+func builtinRegexpQuote(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, regexp.QuoteMeta(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: Regexp
+// return: pattern of arg1
+func builtinRegexpSource(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, regexpArg(pop(vm), vm).String())
+	return 1
+}
+
+// arg1: Regexp, arg2: String
+// return: whether arg2 contains any match
+func builtinRegexpTest(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, regexpArg(args[0], vm).MatchString(stringArg(args[1], vm)))
+	return 1
+}
+
+// arg1: Regexp, arg2: String
+// return: the leftmost match, nil if not found
+func builtinRegexpFind(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	str := stringArg(args[1], vm)
+	loc := regexpArg(args[0], vm).FindStringIndex(str)
+	if loc == nil {
+		push(vm, nil)
+		return 1
+	}
+	push(vm, str[loc[0]:loc[1]])
+	return 1
+}
+
+// arg1: Regexp, arg2: String, arg3: max count of matches, -1(default) means no limit
+// return: Array<String> of successive non-overlapping matches
+func builtinRegexpFindAll(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2 || argCnt == 3)
+	args := popArgs(argCnt, vm)
+	n := -1
+	if argCnt == 3 {
+		n = intArg(args[2], vm)
+	}
+	push(vm, stringArray(regexpArg(args[0], vm).FindAllString(stringArg(args[1], vm), n)))
+	return 1
+}
+
+// arg1: Regexp, arg2: String
+// return: [start, end] of the leftmost match as indexes of characters, nil if not found
+func builtinRegexpFindIndex(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	str := stringArg(args[1], vm)
+	loc := regexpArg(args[0], vm).FindStringIndex(str)
+	if loc == nil {
+		push(vm, nil)
+		return 1
+	}
+	push(vm, types.NewArray([]interface{}{runeIndex(str, loc[0]), runeIndex(str, loc[1])}))
+	return 1
+}
+
+// submatches of str located by loc, groups that do not participate in the match are nil
+func submatches(str string, loc []int) *types.Array {
+	groups := make([]interface{}, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = str[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return types.NewArray(groups)
+}
+
+// arg1: Regexp, arg2: String
+// return: Array of the leftmost match followed by its submatches of groups, nil if not found
+func builtinRegexpMatch(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	str := stringArg(args[1], vm)
+	loc := regexpArg(args[0], vm).FindStringSubmatchIndex(str)
+	if loc == nil {
+		push(vm, nil)
+		return 1
+	}
+	push(vm, submatches(str, loc))
+	return 1
+}
+
+// arg1: Regexp, arg2: String, arg3: max count of matches, -1(default) means no limit
+// return: Array of what match returns for successive non-overlapping matches
+func builtinRegexpMatchAll(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2 || argCnt == 3)
+	args := popArgs(argCnt, vm)
+	n := -1
+	if argCnt == 3 {
+		n = intArg(args[2], vm)
+	}
+	str := stringArg(args[1], vm)
+	locs := regexpArg(args[0], vm).FindAllStringSubmatchIndex(str, n)
+	arr := make([]interface{}, len(locs))
+	for i, loc := range locs {
+		arr[i] = submatches(str, loc)
+	}
+	push(vm, types.NewArray(arr))
+	return 1
+}
+
+// arg1: Regexp, arg2: String
+// return: Object of submatches of named groups in the leftmost match, nil if not found
+func builtinRegexpGroups(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	re, str := regexpArg(args[0], vm), stringArg(args[1], vm)
+	loc := re.FindStringSubmatchIndex(str)
+	if loc == nil {
+		push(vm, nil)
+		return 1
+	}
+	groups := submatches(str, loc).Data
+	obj := types.NewObject()
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			obj.Set(name, groups[i])
+		}
+	}
+	push(vm, obj)
+	return 1
+}
+
+// arg1: Regexp, arg2: String, arg3: replacement, a String in which $1 and ${name} are expanded to
+// submatches, or a callback(match, submatch1, submatch2, ...) whose return value replaces the match
+// return: String with all matches replaced
+func builtinRegexpReplace(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 3)
+	args := popArgs(argCnt, vm)
+	re, str := regexpArg(args[0], vm), stringArg(args[1], vm)
+	if repl, ok := args[2].(string); ok {
+		push(vm, re.ReplaceAllString(str, repl))
+		return 1
+	}
+	vm.assert(callable(args[2]))
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(str, -1) {
+		ret, ok := vm.callback(args[2], submatches(str, loc).Data...)
+		if !ok {
+			return 0
+		}
+		b.WriteString(str[last:loc[0]])
+		fprint(&b, ret)
+		last = loc[1]
+	}
+	b.WriteString(str[last:])
+	push(vm, b.String())
+	return 1
+}
+
+// arg1: Regexp, arg2: String, arg3: max count of substrings, -1(default) means no limit
+// return: Array<String> of substrings between matches
+func builtinRegexpSplit(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2 || argCnt == 3)
+	args := popArgs(argCnt, vm)
+	n := -1
+	if argCnt == 3 {
+		n = intArg(args[2], vm)
+	}
+	push(vm, stringArray(regexpArg(args[0], vm).Split(stringArg(args[1], vm), n)))
+	return 1
+}
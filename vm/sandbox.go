@@ -0,0 +1,106 @@
+package vm
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox restricts capabilities of script. Builtin functions throw an Error of kind
+// Permission if script oversteps.
+type Sandbox struct {
+	AllowExec  bool     // run commands
+	AllowEnv   bool     // modify environment variables and working directory of process
+	ReadRoots  []string // files under these directories can be read
+	WriteRoots []string // files under these directories can be read, created, modified and removed
+}
+
+// SetSandbox restricts capabilities of script, nil means unrestricted
+func (vm *VM) SetSandbox(sandbox *Sandbox) {
+	vm.sandbox = sandbox
+}
+
+func permissionDenied(op, target string) error {
+	return fmt.Errorf("sandbox: %s %s: %w", op, target, fs.ErrPermission)
+}
+
+func (s *Sandbox) checkExec(command string) error {
+	if s == nil || s.AllowExec {
+		return nil
+	}
+	return permissionDenied("exec", command)
+}
+
+func (s *Sandbox) checkEnv(op, target string) error {
+	if s == nil || s.AllowEnv {
+		return nil
+	}
+	return permissionDenied(op, target)
+}
+
+func (s *Sandbox) checkRead(path string) error {
+	if s == nil {
+		return nil
+	}
+	path = realPath(path)
+	if withinRoots(path, s.ReadRoots) || withinRoots(path, s.WriteRoots) {
+		return nil
+	}
+	return permissionDenied("read", path)
+}
+
+func (s *Sandbox) checkWrite(path string) error {
+	if s == nil {
+		return nil
+	}
+	path = realPath(path)
+	if withinRoots(path, s.WriteRoots) {
+		return nil
+	}
+	return permissionDenied("write", path)
+}
+
+// check access of file opened with flag
+func (s *Sandbox) checkOpen(path string, flag int) error {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		if err := s.checkWrite(path); err != nil {
+			return err
+		}
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR) != os.O_WRONLY {
+		return s.checkRead(path)
+	}
+	return nil
+}
+
+// absolute path with symbolic links resolved, so that links can not escape from roots.
+// path may not exist yet, e.g. file to create, then its directory is resolved.
+func realPath(path string) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	dir, base := filepath.Split(path)
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		return filepath.Join(real, base)
+	}
+	return path
+}
+
+func withinRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(realPath(root), path)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package vm
 
 import (
+	"fmt"
+	"gscript/proto"
 	"gscript/vm/types"
 )
 
@@ -11,6 +13,10 @@ type stackFrame struct {
 	pc          uint32
 	upValues    []*types.GsValue
 	text        []byte
+	lineTable   []proto.LineInfo
+	filepath    string
+	name        string // name of function, empty for top level code of a module
+	depth       int    // depth of nested function calls
 	tryInfos    []tryInfo
 }
 
@@ -20,21 +26,43 @@ func newFuncFrame() *stackFrame {
 	}
 }
 
+// source line and column of the instruction being executed, zero if unknown
+func (sf *stackFrame) line() (line, column uint32) {
+	if sf.pc == 0 {
+		return 0, 0
+	}
+	// pc has been moved past the opcode
+	return proto.SearchLineTable(sf.lineTable, sf.pc-1)
+}
+
+// source position in form of file:line:col, line and column are omitted if unknown
+func formatPosition(file string, line, column int) string {
+	if line == 0 {
+		return file
+	}
+	if column == 0 {
+		return fmt.Sprintf("%s:%d", file, line)
+	}
+	return fmt.Sprintf("%s:%d:%d", file, line, column)
+}
+
 type tryInfo struct {
 	curVarCnt uint32
 	catchAddr uint32
+	stackSize int // size of evaluation stack when entering try block
 }
 
-func (sf *stackFrame) pushTryInfo(addr uint32, varCnt uint32) {
+func (sf *stackFrame) pushTryInfo(addr uint32, varCnt uint32, stackSize int) {
 	sf.tryInfos = append(sf.tryInfos, tryInfo{
 		curVarCnt: varCnt,
 		catchAddr: addr,
+		stackSize: stackSize,
 	})
 }
 
-func (sf *stackFrame) popTryInfo() (addr, varCnt uint32) {
+func (sf *stackFrame) popTryInfo() tryInfo {
 	last := len(sf.tryInfos) - 1
 	info := sf.tryInfos[last]
 	sf.tryInfos = sf.tryInfos[:last]
-	return info.catchAddr, info.curVarCnt
+	return info
 }
@@ -0,0 +1,350 @@
+package vm
+
+import (
+	"fmt"
+	"gscript/vm/types"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// builtin functions of std library strings, indexes and lengths of strings are counted in
+// characters as other builtin functions do.
+
+// pop arguments of builtin function, the first argument is at index 0
+func popArgs(argCnt int, vm *VM) []interface{} {
+	args := make([]interface{}, argCnt)
+	for i := argCnt - 1; i >= 0; i-- {
+		args[i] = pop(vm)
+	}
+	return args
+}
+
+func stringArg(arg interface{}, vm *VM) string {
+	str, ok := arg.(string)
+	vm.assert(ok)
+	return str
+}
+
+func intArg(arg interface{}, vm *VM) int {
+	num, ok := arg.(int64)
+	vm.assert(ok)
+	return int(num)
+}
+
+// arg1: String, arg2: separator, arg3: max count of substrings, -1(default) means no limit
+// return: Array<String>
+func builtinStringsSplit(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2 || argCnt == 3)
+	args := popArgs(argCnt, vm)
+	n := -1
+	if argCnt == 3 {
+		n = intArg(args[2], vm)
+	}
+	strs := strings.SplitN(stringArg(args[0], vm), stringArg(args[1], vm), n)
+	push(vm, stringArray(strs))
+	return 1
+}
+
+// arg1: Array, arg2: separator, default ""
+// return: String, elements are formatted as print does
+func builtinStringsJoin(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1 || argCnt == 2)
+	args := popArgs(argCnt, vm)
+	arr, ok := args[0].(*types.Array)
+	vm.assert(ok)
+	var sep string
+	if argCnt == 2 {
+		sep = stringArg(args[1], vm)
+	}
+	var b strings.Builder
+	for i, val := range arr.Data {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		fprint(&b, val)
+	}
+	push(vm, b.String())
+	return 1
+}
+
+// arg1: String, arg2: cutset, white spaces are trimmed if cutset is nil, arg3: "both", "left" or "right"
+// return: String
+func builtinStringsTrim(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 3)
+	args := popArgs(argCnt, vm)
+	str, side := stringArg(args[0], vm), stringArg(args[2], vm)
+	trim := func(r rune) bool { return unicode.IsSpace(r) }
+	if args[1] != nil {
+		cutset := stringArg(args[1], vm)
+		trim = func(r rune) bool { return strings.ContainsRune(cutset, r) }
+	}
+	switch side {
+	case "left":
+		str = strings.TrimLeftFunc(str, trim)
+	case "right":
+		str = strings.TrimRightFunc(str, trim)
+	default:
+		str = strings.TrimFunc(str, trim)
+	}
+	push(vm, str)
+	return 1
+}
+
+// arg1: String, arg2: prefix
+// return: String
+func builtinStringsTrimPrefix(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, strings.TrimPrefix(stringArg(args[0], vm), stringArg(args[1], vm)))
+	return 1
+}
+
+// arg1: String, arg2: suffix
+// return: String
+func builtinStringsTrimSuffix(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, strings.TrimSuffix(stringArg(args[0], vm), stringArg(args[1], vm)))
+	return 1
+}
+
+// arg1: String, arg2: old, arg3: new, arg4: max count of replacements, -1(default) means no limit
+// return: String
+func builtinStringsReplace(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 3 || argCnt == 4)
+	args := popArgs(argCnt, vm)
+	n := -1
+	if argCnt == 4 {
+		n = intArg(args[3], vm)
+	}
+	push(vm, strings.Replace(stringArg(args[0], vm), stringArg(args[1], vm), stringArg(args[2], vm), n))
+	return 1
+}
+
+// arg1: String, arg2: substring
+// return: Boolean
+func builtinStringsContains(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, strings.Contains(stringArg(args[0], vm), stringArg(args[1], vm)))
+	return 1
+}
+
+// arg1: String, arg2: substring
+// return: index of the first substring, -1 if not found
+func builtinStringsIndex(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	str := stringArg(args[0], vm)
+	push(vm, runeIndex(str, strings.Index(str, stringArg(args[1], vm))))
+	return 1
+}
+
+// arg1: String, arg2: substring
+// return: index of the last substring, -1 if not found
+func builtinStringsLastIndex(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	str := stringArg(args[0], vm)
+	push(vm, runeIndex(str, strings.LastIndex(str, stringArg(args[1], vm))))
+	return 1
+}
+
+// arg1: String, arg2: prefix
+// return: Boolean
+func builtinStringsHasPrefix(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, strings.HasPrefix(stringArg(args[0], vm), stringArg(args[1], vm)))
+	return 1
+}
+
+// arg1: String, arg2: suffix
+// return: Boolean
+func builtinStringsHasSuffix(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, strings.HasSuffix(stringArg(args[0], vm), stringArg(args[1], vm)))
+	return 1
+}
+
+// arg1: String
+// return: String
+func builtinStringsUpper(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, strings.ToUpper(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: String
+// return: String
+func builtinStringsLower(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, strings.ToLower(stringArg(pop(vm), vm)))
+	return 1
+}
+
+// arg1: String, arg2: count
+// return: String
+func builtinStringsRepeat(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	count := intArg(args[1], vm)
+	if count < 0 {
+		throw(fmt.Errorf("negative repeat count %d: %w", count, errInvalidArg), vm)
+		return 0
+	}
+	push(vm, strings.Repeat(stringArg(args[0], vm), count))
+	return 1
+}
+
+// arg1: String, arg2: length, arg3: pad string, arg4: pad at start if true, otherwise at end
+// return: String padded to length
+func builtinStringsPad(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 4)
+	args := popArgs(argCnt, vm)
+	str, length, pad := stringArg(args[0], vm), intArg(args[1], vm), []rune(stringArg(args[2], vm))
+	atStart, ok := args[3].(bool)
+	vm.assert(ok)
+	n := length - utf8.RuneCountInString(str)
+	if n <= 0 || len(pad) == 0 {
+		push(vm, str)
+		return 1
+	}
+	// pad is repeated and truncated to fill n characters
+	padding := make([]rune, n)
+	for i := range padding {
+		padding[i] = pad[i%len(pad)]
+	}
+	if atStart {
+		push(vm, string(padding)+str)
+	} else {
+		push(vm, str+string(padding))
+	}
+	return 1
+}
+
+// arg1: String
+// return: Array<String> split around white spaces
+func builtinStringsFields(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, stringArray(strings.Fields(stringArg(pop(vm), vm))))
+	return 1
+}
+
+// arg1: String, arg2: base, 0 means base is implied by prefix of the string
+// return: Integer
+func builtinStringsParseInt(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	base := intArg(args[1], vm)
+	if base != 0 && (base < 2 || base > 36) {
+		throw(fmt.Errorf("base %d is not 0 or between 2 and 36: %w", base, errInvalidArg), vm)
+		return 0
+	}
+	num, err := strconv.ParseInt(stringArg(args[0], vm), base, 64)
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, num)
+	return 1
+}
+
+// arg1: String
+// return: Float
+func builtinStringsParseFloat(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	num, err := strconv.ParseFloat(stringArg(pop(vm), vm), 64)
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, num)
+	return 1
+}
+
+// arg1: Number, arg2: count of digits after the decimal point, default 0
+// return: String
+func builtinStringsToFixed(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1 || argCnt == 2)
+	args := popArgs(argCnt, vm)
+	var num float64
+	switch v := args[0].(type) {
+	case int64:
+		num = float64(v)
+	case float64:
+		num = v
+	default:
+		vm.assert(false)
+	}
+	var digits int
+	if argCnt == 2 {
+		digits = intArg(args[1], vm)
+	}
+	if digits < 0 || digits > 100 {
+		throw(fmt.Errorf("digits %d is not between 0 and 100: %w", digits, errInvalidArg), vm)
+		return 0
+	}
+	push(vm, strconv.FormatFloat(num, 'f', digits, 64))
+	return 1
+}
+
+func stringArray(strs []string) *types.Array {
+	arr := make([]interface{}, len(strs))
+	for i, str := range strs {
+		arr[i] = str
+	}
+	return types.NewArray(arr)
+}
+
+// convert byte offset of str to index of character, -1 is kept
+func runeIndex(str string, offset int) int64 {
+	if offset < 0 {
+		return -1
+	}
+	return int64(utf8.RuneCountInString(str[:offset]))
+}
+
+// characters of the string indexed or measured last, so that visiting a string by index,
+// e.g. 'for (let i = 0; i < len(s); i++) s[i]', takes O(1) per character instead of O(n)
+type runeCache struct {
+	str     string
+	count   int   // count of characters
+	offsets []int // byte offsets of characters, built when str is indexed
+}
+
+func (c *runeCache) load(str string) {
+	if str == c.str {
+		return
+	}
+	c.str, c.count, c.offsets = str, utf8.RuneCountInString(str), nil
+}
+
+// count of characters of str
+func (c *runeCache) len(str string) int {
+	c.load(str)
+	return c.count
+}
+
+// the idx-th character of str, false if idx is out of range
+func (c *runeCache) runeAt(str string, idx int64) (rune, bool) {
+	c.load(str)
+	if idx < 0 || idx >= int64(c.count) {
+		return 0, false
+	}
+	offset := int(idx)
+	if c.count != len(str) {
+		if c.offsets == nil {
+			c.offsets = make([]int, 0, c.count)
+			for i := range str {
+				c.offsets = append(c.offsets, i)
+			}
+		}
+		offset = c.offsets[idx]
+	}
+	r, _ := utf8.DecodeRuneInString(str[offset:])
+	return r, true
+}
@@ -0,0 +1,239 @@
+package vm
+
+import (
+	"gscript/vm/types"
+	"time"
+
+	// time zone database for converting time zones on systems without it
+	_ "time/tzdata"
+)
+
+// builtin functions of std library time, durations are Integers of nanoseconds
+
+// error of invalid time zone or duration, its kind is Invalid
+type timeError string
+
+func (e timeError) Error() string {
+	return string(e)
+}
+
+func timeArg(arg interface{}, vm *VM) time.Time {
+	t, ok := arg.(*types.Time)
+	vm.assert(ok)
+	return t.Time
+}
+
+// "Local", "UTC" or name of IANA time zone database such as "Asia/Shanghai"
+func loadLocation(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, timeError("unknown time zone " + name)
+	}
+	return loc, nil
+}
+
+// return: Time
+func builtinTimeNow(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 0)
+	push(vm, types.NewTime(time.Now()))
+	return 1
+}
+
+// arg1: instance of class Time, arg2: Time
+// set fields of the instance from Time
+func builtinTimeInit(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	obj, ok := args[0].(*types.Object)
+	vm.assert(ok)
+	t := timeArg(args[1], vm)
+	zone, offset := t.Zone()
+	obj.Set("_time", args[1])
+	obj.Set("year", int64(t.Year()))
+	obj.Set("month", int64(t.Month()))
+	obj.Set("day", int64(t.Day()))
+	obj.Set("hour", int64(t.Hour()))
+	obj.Set("minute", int64(t.Minute()))
+	obj.Set("second", int64(t.Second()))
+	obj.Set("nanosecond", int64(t.Nanosecond()))
+	obj.Set("weekday", int64(t.Weekday()))
+	obj.Set("yearDay", int64(t.YearDay()))
+	obj.Set("unix", t.Unix())
+	obj.Set("unixMilli", t.UnixMilli())
+	obj.Set("unixNano", t.UnixNano())
+	obj.Set("zone", zone)
+	obj.Set("offset", int64(offset))
+	return 0
+}
+
+// arg1: seconds, arg2: nanoseconds since January 1, 1970 UTC
+// return: Time in local time zone
+func builtinTimeUnix(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	sec, ok1 := args[0].(int64)
+	nsec, ok2 := args[1].(int64)
+	vm.assert(ok1 && ok2)
+	push(vm, types.NewTime(time.Unix(sec, nsec)))
+	return 1
+}
+
+// arg1: year, arg2: month, arg3: day, arg4: hour, arg5: minute, arg6: second, arg7: nanosecond, arg8: time zone
+// return: Time, values out of range are normalized, e.g. October 32 is November 1
+func builtinTimeDate(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 8)
+	args := popArgs(argCnt, vm)
+	var fields [7]int
+	for i := range fields {
+		fields[i] = intArg(args[i], vm)
+	}
+	loc, err := loadLocation(stringArg(args[7], vm))
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	t := time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], fields[6], loc)
+	push(vm, types.NewTime(t))
+	return 1
+}
+
+// arg1: layout, arg2: String, arg3: time zone used if str has no time zone information
+// return: Time
+func builtinTimeParse(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 3)
+	args := popArgs(argCnt, vm)
+	layout, str := stringArg(args[0], vm), stringArg(args[1], vm)
+	loc, err := loadLocation(stringArg(args[2], vm))
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	t, err := time.ParseInLocation(layout, str, loc)
+	if err != nil {
+		throw(timeError(err.Error()), vm)
+		return 0
+	}
+	push(vm, types.NewTime(t))
+	return 1
+}
+
+// arg1: Time, arg2: layout
+// return: String
+func builtinTimeFormat(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, timeArg(args[0], vm).Format(stringArg(args[1], vm)))
+	return 1
+}
+
+// arg1: Time, arg2: duration
+// return: Time
+func builtinTimeAdd(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	d, ok := args[1].(int64)
+	vm.assert(ok)
+	push(vm, types.NewTime(timeArg(args[0], vm).Add(time.Duration(d))))
+	return 1
+}
+
+// arg1: Time, arg2: years, arg3: months, arg4: days
+// return: Time
+func builtinTimeAddDate(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 4)
+	args := popArgs(argCnt, vm)
+	t := timeArg(args[0], vm).AddDate(intArg(args[1], vm), intArg(args[2], vm), intArg(args[3], vm))
+	push(vm, types.NewTime(t))
+	return 1
+}
+
+// arg1: Time, arg2: Time
+// return: duration of arg1 - arg2, monotonic clock is used if both are got by now
+func builtinTimeSub(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	push(vm, int64(timeArg(args[0], vm).Sub(timeArg(args[1], vm))))
+	return 1
+}
+
+// arg1: Time
+// return: duration elapsed since arg1, monotonic clock is used if arg1 is got by now
+func builtinTimeSince(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	push(vm, int64(time.Since(timeArg(pop(vm), vm))))
+	return 1
+}
+
+// arg1: Time, arg2: Time
+// return: -1 if arg1 is before arg2, 1 if after, otherwise 0
+func builtinTimeCompare(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	t1, t2 := timeArg(args[0], vm), timeArg(args[1], vm)
+	var res int64
+	switch {
+	case t1.Before(t2):
+		res = -1
+	case t1.After(t2):
+		res = 1
+	}
+	push(vm, res)
+	return 1
+}
+
+// arg1: Time, arg2: time zone
+// return: the same instant in time zone arg2
+func builtinTimeIn(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 2)
+	args := popArgs(argCnt, vm)
+	t := timeArg(args[0], vm)
+	loc, err := loadLocation(stringArg(args[1], vm))
+	if err != nil {
+		throw(err, vm)
+		return 0
+	}
+	push(vm, types.NewTime(t.In(loc)))
+	return 1
+}
+
+// arg1: milliseconds, Integer or Float
+// the sleep is interrupted once context of vm is done
+func builtinTimeSleep(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	ms := numberArg(pop(vm), vm)
+	timer := time.NewTimer(time.Duration(ms * float64(time.Millisecond)))
+	defer timer.Stop()
+	if vm.ctx == nil {
+		<-timer.C
+		return 0
+	}
+	select {
+	case <-timer.C:
+	case <-vm.ctx.Done():
+		vm.limitExceeded(vm.ctx.Err())
+	}
+	return 0
+}
+
+// arg1: String such as "1h30m", "1.5s" and "-300ms"
+// return: duration
+func builtinTimeParseDuration(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	d, err := time.ParseDuration(stringArg(pop(vm), vm))
+	if err != nil {
+		throw(timeError(err.Error()), vm)
+		return 0
+	}
+	push(vm, int64(d))
+	return 1
+}
+
+// arg1: duration
+// return: String such as "1h30m0s"
+func builtinTimeFormatDuration(argCnt int, vm *VM) (retCnt int) {
+	vm.assert(argCnt == 1)
+	d, ok := pop(vm).(int64)
+	vm.assert(ok)
+	push(vm, time.Duration(d).String())
+	return 1
+}
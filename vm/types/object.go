@@ -7,16 +7,24 @@ type KV struct {
 	Val interface{}
 }
 
-// when kv count is <= 8, use Array to stores kvs, otherwise use Map
+// kvs are stored in Array in insertion order, deleted kvs are left as tombstones until they
+// are more than the others. When Array is longer than 8, Map indexes positions of keys in Array.
 type Object struct {
-	Array []KV
-	Map   map[interface{}]interface{}
+	Array   []KV
+	Map     map[interface{}]int
+	deleted int      // count of tombstones in Array
+	IsError bool     // created or initialized by builtin Error
+	Class   *Closure // constructor of class if the object is created by 'new'
 }
 
+// key of deleted kvs in Array
+type tombstone struct{}
+
 func NewObjectN(cap int) *Object {
 	obj := &Object{}
 	if cap > MaxArrayCap {
-		obj.Map = make(map[interface{}]interface{}, cap)
+		obj.Array = make([]KV, 0, cap)
+		obj.Map = make(map[interface{}]int, cap)
 		return obj
 	}
 	obj.Array = make([]KV, 0, MaxArrayCap)
@@ -28,79 +36,108 @@ func NewObject() *Object {
 }
 
 func (obj *Object) Set(k, v interface{}) {
-	if obj.Map != nil {
-		obj.Map[k] = v
+	if idx, ok := obj.index(k); ok {
+		obj.Array[idx].Val = v
 		return
 	}
-	for i := range obj.Array {
-		if obj.Array[i].Key == k {
-			obj.Array[i].Val = v
-			return
-		}
-	}
-	if len(obj.Array) < MaxArrayCap {
-		obj.Array = append(obj.Array, KV{k, v})
+	obj.Array = append(obj.Array, KV{k, v})
+	if obj.Map != nil {
+		obj.Map[k] = len(obj.Array) - 1
 		return
 	}
-	obj.Map = make(map[interface{}]interface{}, 16)
-	for _, kv := range obj.Array {
-		obj.Map[kv.Key] = kv.Val
+	if len(obj.Array) > MaxArrayCap {
+		obj.Map = make(map[interface{}]int, 2*len(obj.Array))
+		obj.reindex()
 	}
-	obj.Map[k] = v
 }
 
-func (obj *Object) Get(k interface{}) interface{} {
+// index positions of keys in Array except tombstones
+func (obj *Object) reindex() {
+	for i, kv := range obj.Array {
+		if _, ok := kv.Key.(tombstone); !ok {
+			obj.Map[kv.Key] = i
+		}
+	}
+}
+
+// position of k in Array
+func (obj *Object) index(k interface{}) (int, bool) {
 	if obj.Map != nil {
-		return obj.Map[k]
+		idx, ok := obj.Map[k]
+		return idx, ok
 	}
 	for i := range obj.Array {
 		if obj.Array[i].Key == k {
-			return obj.Array[i].Val
+			return i, true
 		}
 	}
+	return 0, false
+}
+
+func (obj *Object) Get(k interface{}) interface{} {
+	if idx, ok := obj.index(k); ok {
+		return obj.Array[idx].Val
+	}
 	return nil
 }
 
 func (obj *Object) KVCount() int {
-	if obj.Map != nil {
-		return len(obj.Map)
-	}
-	return len(obj.Array)
+	return len(obj.Array) - obj.deleted
 }
 
 func (obj *Object) Clone() *Object {
+	arr := make([]KV, len(obj.Array), cap(obj.Array))
+	copy(arr, obj.Array)
+	clone := &Object{Array: arr, deleted: obj.deleted, IsError: obj.IsError, Class: obj.Class}
 	if obj.Map != nil {
-		m := make(map[interface{}]interface{}, len(obj.Map))
-		for k, v := range obj.Map {
-			m[k] = v
+		clone.Map = make(map[interface{}]int, len(obj.Map))
+		for k, idx := range obj.Map {
+			clone.Map[k] = idx
 		}
-		return &Object{Map: m}
 	}
-	arr := make([]KV, len(obj.Array), cap(obj.Array))
-	copy(arr, obj.Array)
-	return &Object{Array: arr}
+	return clone
 }
 
+// the kv is replaced by a tombstone so that positions of the following kvs are kept, tombstones
+// are removed once they are more than half of Array, which makes deleting O(1) amortized
 func (obj *Object) Delete(key interface{}) {
+	idx, ok := obj.index(key)
+	if !ok {
+		return
+	}
+	obj.Array[idx] = KV{Key: tombstone{}}
+	obj.deleted++
 	if obj.Map != nil {
 		delete(obj.Map, key)
-		return
 	}
-	for i := range obj.Array {
-		if obj.Array[i].Key == key {
-			obj.Array[i].Val = nil
-		}
+	if obj.deleted > len(obj.Array)/2 {
+		obj.compact()
 	}
 }
 
-func (obj *Object) ForEach(cb func(k, v interface{})) {
-	if obj.Map != nil {
-		for k, v := range obj.Map {
-			cb(k, v)
+// remove tombstones from Array
+func (obj *Object) compact() {
+	arr := obj.Array[:0]
+	for _, kv := range obj.Array {
+		if _, ok := kv.Key.(tombstone); !ok {
+			arr = append(arr, kv)
 		}
-		return
 	}
+	// release values in the rest of Array
+	for i := len(arr); i < len(obj.Array); i++ {
+		obj.Array[i] = KV{}
+	}
+	obj.Array, obj.deleted = arr, 0
+	if obj.Map != nil {
+		obj.reindex()
+	}
+}
+
+// iterate kvs in insertion order
+func (obj *Object) ForEach(cb func(k, v interface{})) {
 	for _, kv := range obj.Array {
-		cb(kv.Key, kv.Val)
+		if _, ok := kv.Key.(tombstone); !ok {
+			cb(kv.Key, kv.Val)
+		}
 	}
 }
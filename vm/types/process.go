@@ -0,0 +1,18 @@
+package types
+
+import (
+	"bytes"
+	"os/exec"
+)
+
+type Process struct {
+	Cmd    *exec.Cmd
+	Done   chan struct{} // closed once the process exits and its output is copied
+	Err    error         // error of waiting for the process, valid after Done is closed
+	Stdout *bytes.Buffer // captured output, nil if it is not captured
+	Stderr *bytes.Buffer
+}
+
+func NewProcess(cmd *exec.Cmd) *Process {
+	return &Process{Cmd: cmd, Done: make(chan struct{})}
+}
@@ -0,0 +1,11 @@
+package types
+
+import "regexp"
+
+type Regexp struct {
+	Regexp *regexp.Regexp
+}
+
+func NewRegexp(re *regexp.Regexp) *Regexp {
+	return &Regexp{Regexp: re}
+}
@@ -0,0 +1,11 @@
+package types
+
+import "time"
+
+type Time struct {
+	Time time.Time
+}
+
+func NewTime(t time.Time) *Time {
+	return &Time{Time: t}
+}
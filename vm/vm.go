@@ -1,10 +1,12 @@
 package vm
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"gscript/proto"
-	"os"
+	"gscript/vm/types"
+	"strings"
 	"unsafe"
 )
 
@@ -15,25 +17,222 @@ type VM struct {
 	curProto          *protoFrame
 	builtinFuncFailed bool
 	curCallingBuiltin string
+	hostValues        []interface{}
+	sandbox           *Sandbox
+	parents           map[*proto.BasicInfo]interface{} // parent classes of classes, see INS_INHERIT
+	running           bool                             // executing script by Run or CallValue
+	barrier           *stackFrame                      // frame waiting for the innermost nested call, see callNested
+	nested            int                              // depth of nested calls, see callNested
+	runes             runeCache                        // characters of the string indexed last
+
+	limits Limits
+	ctx    context.Context
+	steps  uint64 // count of instructions executed by the current Run or Call
 }
 
 func NewVM(protos []proto.Proto, stdlibs []proto.Proto) *VM {
+	bindProtoInfo(protos)
+	bindProtoInfo(stdlibs)
 	return &VM{
 		protos:   protos,
 		stdlibs:  stdlibs,
 		curProto: newProtoFrame(protos[0]),
+		parents:  map[*proto.BasicInfo]interface{}{},
 	}
 }
 
-func (vm *VM) Run() {
+// functions of a proto may be called from other protos, so they should know which file they belong to
+// and their names for tracebacks
+func bindProtoInfo(protos []proto.Proto) {
+	for i := range protos {
+		for j := range protos[i].Funcs {
+			_func := &protos[i].Funcs[j]
+			_func.Info.FilePath = protos[i].FilePath
+			_func.Info.Name = _func.Name
+		}
+		for j := range protos[i].AnonymousFuncs {
+			_func := &protos[i].AnonymousFuncs[j]
+			_func.Info.FilePath = protos[i].FilePath
+			if _func.Name == "" {
+				_func.Info.Name = "<anonymous>"
+			} else {
+				_func.Info.Name = _func.Name + ".__self"
+			}
+		}
+	}
+}
+
+// Run executes the main proto. Runtime errors are returned as *RuntimeError, and *ExitError
+// is returned if script calls exit.
+func (vm *VM) Run() (err error) {
+	defer vm.recoverError(&err)
+	vm.running = true
+	defer func() { vm.running = false }()
+	vm.steps = 0
 	for {
 		if vm.stopped {
 			break
 		}
-		instruction := vm.curProto.frame.text[vm.curProto.frame.pc]
-		vm.curProto.frame.pc++
-		Execute(vm, instruction)
+		vm.step()
+	}
+	return nil
+}
+
+// Call calls the function defined in main proto by name and returns its return values.
+// It should be called after Run finishes.
+func (vm *VM) Call(name string, args ...interface{}) (rets []interface{}, err error) {
+	if !vm.stopped || vm.curProto.prev != nil {
+		return nil, fmt.Errorf("can not call function '%s' before script finishes running", name)
+	}
+	funcs := vm.protos[0].Funcs
+	for i := range funcs {
+		if funcs[i].Name == name {
+			genClosure(vm, &funcs[i])
+			return vm.CallValue(vm.curProto.stack.pop(), args...)
+		}
+	}
+	return nil, fmt.Errorf("function '%s' is not defined", name)
+}
+
+// CallValue calls fn with args and returns all of its return values, fn can be a closure or a
+// builtin function. It can be called after Run finishes, or by Go functions called by script
+// while it is running, e.g. to call a callback passed to them.
+//
+// Exception not caught by fn is returned as *RuntimeError whose Uncaught is true. Go functions
+// called by script can return the error to rethrow the exception where they are called.
+func (vm *VM) CallValue(fn interface{}, args ...interface{}) (rets []interface{}, err error) {
+	if !callable(fn) {
+		return nil, fmt.Errorf("can not call a %s", getType(fn))
+	}
+	if !vm.running {
+		if !vm.stopped || vm.curProto.prev != nil {
+			return nil, fmt.Errorf("can not call function before script finishes running")
+		}
+		vm.steps = 0
+		vm.running = true
+		defer func() { vm.running = false }()
+	}
+	return vm.callNested(fn, args, -1)
+}
+
+// Running reports whether script is running, i.e. CallValue is called by Go functions called by script
+func (vm *VM) Running() bool {
+	return vm.running
+}
+
+// nested calls run on Go stack, e.g. script recursing through callbacks of builtin functions,
+// so their depth is limited even if Limits.MaxCallDepth is 0
+const maxNestedCalls = 10000
+
+// call fn with args and run until it returns, negative wantRtnCnt means keeping all return values.
+// Frames of the caller are protected by barrier, exceptions not caught by fn are returned as
+// *RuntimeError rather than caught by them, see _throw.
+func (vm *VM) callNested(fn interface{}, args []interface{}, wantRtnCnt int) (rets []interface{}, err error) {
+	pf, frame := vm.curProto, vm.curProto.frame
+	stack := pf.stack
+	base := len(stack.Buf)
+	barrier, calling := vm.barrier, vm.curCallingBuiltin
+	vm.barrier = frame
+	vm.nested++
+	defer func() {
+		vm.barrier, vm.curCallingBuiltin = barrier, calling
+		vm.nested--
+		// unwind the frames of callee if it fails
+		if err != nil {
+			vm.curProto, pf.frame = pf, frame
+			vm.builtinFuncFailed = false
+			stack.popN(len(stack.Buf) - base)
+		}
+	}()
+	defer vm.recoverError(&err)
+	if vm.nested > maxNestedCalls {
+		vm.limitExceeded(ErrCallDepthLimit)
+	}
+
+	for _, arg := range args {
+		stack.Push(arg)
+	}
+	call(fn, vm, uint32(len(args)), wantRtnCnt)
+	for vm.curProto != pf || pf.frame != frame {
+		vm.step()
+	}
+	rets = make([]interface{}, len(stack.Buf)-base)
+	copy(rets, stack.Buf[base:])
+	stack.popN(len(rets))
+	return rets, nil
+}
+
+// call fn with args in builtin functions and return its first return value. If fn throws an
+// exception, it is rethrown where the builtin function is called and false is returned, the
+// builtin function should return immediately as it does after calling throw.
+func (vm *VM) callback(fn interface{}, args ...interface{}) (ret interface{}, ok bool) {
+	vm.assert(callable(fn))
+	rets, err := vm.callNested(fn, args, 1)
+	if err != nil {
+		throw(err, vm)
+		return nil, false
+	}
+	return rets[0], true
+}
+
+func callable(fn interface{}) bool {
+	switch fn.(type) {
+	case *types.Closure, *builtinFunc, *method:
+		return true
 	}
+	return false
+}
+
+// RunChunk runs main proto from start, it is used when main proto is generated chunk by
+// chunk, e.g. by REPL. Variables of the previous chunks are kept, and values left in
+// evaluation stack by the chunk are returned. If the chunk fails, variables declared by it
+// are discarded.
+func (vm *VM) RunChunk(main proto.Proto, start uint32) (rets []interface{}, err error) {
+	if !vm.stopped || vm.curProto.prev != nil {
+		return nil, fmt.Errorf("can not run chunk before script finishes running")
+	}
+	vm.protos[0] = main
+	bindProtoInfo(vm.protos[:1])
+	pf := vm.curProto
+	top := pf.topFrame
+	top.text, top.lineTable = main.Text, main.LineTable
+	pf.funcTable, pf.anonymousTable = main.Funcs, main.AnonymousFuncs
+	stack := pf.stack
+	base, varCnt := len(stack.Buf), len(top.symbolTable.values)
+
+	top.pc = start
+	vm.stopped = false
+	if err = vm.Run(); err != nil {
+		vm.curProto, pf.frame = pf, top
+		top.tryInfos = nil
+		stack.popN(len(stack.Buf) - base)
+		top.symbolTable.resizeTo(varCnt)
+		vm.stopped = true
+		return nil, err
+	}
+	rets = make([]interface{}, len(stack.Buf)-base)
+	copy(rets, stack.Buf[base:])
+	stack.popN(len(rets))
+	return rets, nil
+}
+
+// GlobalValue returns value of the top level variable of main proto indexed by idx
+func (vm *VM) GlobalValue(idx uint32) (val interface{}, ok bool) {
+	pf := vm.curProto
+	for pf.prev != nil {
+		pf = pf.prev
+	}
+	values := pf.topFrame.symbolTable.values
+	if idx >= uint32(len(values)) {
+		return nil, false
+	}
+	return values[idx].Value, true
+}
+
+// SetHostValues sets values provided by host application, they are indexed by
+// compiler.Host when compiling
+func (vm *VM) SetHostValues(values []interface{}) {
+	vm.hostValues = values
 }
 
 func (vm *VM) Stop() {
@@ -47,23 +246,114 @@ func (vm *VM) getOpNum() uint32 {
 	return v
 }
 
+const maxTracebackFrames = 50
+
+// traceback of all calling frames, the most recent call last
+func (vm *VM) traceback() string {
+	var frames []*stackFrame
+	for pf := vm.curProto; pf != nil; pf = pf.prev {
+		for frame := pf.frame; frame != nil; frame = frame.prev {
+			frames = append(frames, frame)
+		}
+	}
+	var buf strings.Builder
+	buf.WriteString("Traceback (most recent call last):\n")
+	for i := len(frames) - 1; i >= 0; i-- {
+		// only show the outermost and innermost frames of a deep stack, e.g. infinite recursion
+		if len(frames) > maxTracebackFrames && i == len(frames)-maxTracebackFrames/2-1 {
+			omitted := len(frames) - maxTracebackFrames
+			fmt.Fprintf(&buf, "  ... %d frames omitted ...\n", omitted)
+			i -= omitted - 1
+			continue
+		}
+		frame := frames[i]
+		name := frame.name
+		if name == "" {
+			name = "<module>"
+		}
+		fmt.Fprintf(&buf, "  File \"%s\"", frame.filepath)
+		if line, _ := frame.line(); line != 0 {
+			fmt.Fprintf(&buf, ", line %d", line)
+		}
+		fmt.Fprintf(&buf, ", in %s\n", name)
+	}
+	return buf.String()
+}
+
+// RuntimeError is returned by VM if executing script failed
+type RuntimeError struct {
+	File      string
+	Line      int // 0 if unknown
+	Column    int // 0 if unknown
+	Msg       string
+	Traceback string      // calling frames where the error occurs
+	Uncaught  bool        // caused by an uncaught exception
+	Exception interface{} // the uncaught exception
+	Err       error       // cause of the error if it is raised by Limits or context, see Unwrap
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[%s] runtime error: %s", formatPosition(e.File, e.Line, e.Column), e.Msg)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// ExitError is returned by VM if script calls exit
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// runtime error occurs at the instruction being executed
+func (vm *VM) newRuntimeError(msg string) *RuntimeError {
+	frame := vm.curProto.frame
+	line, column := frame.line()
+	return &RuntimeError{
+		File:      frame.filepath,
+		Line:      int(line),
+		Column:    int(column),
+		Msg:       msg,
+		Traceback: vm.traceback(),
+	}
+}
+
 func (vm *VM) exit(format string, args ...interface{}) {
-	fmt.Printf("[%s] ", vm.curProto.filepath)
-	exit(format, args...)
+	panic(vm.newRuntimeError(fmt.Sprintf(format, args...)))
 }
 
+// runtime error raised where vm is not reachable, VM will recover it and report position
+type runtimeError string
+
 func exit(format string, args ...interface{}) {
-	fmt.Printf("runtime error: ")
-	fmt.Printf(format, args...)
-	os.Exit(0)
+	panic(runtimeError(fmt.Sprintf(format, args...)))
+}
+
+// convert the panic raised when executing script to err
+func (vm *VM) recoverError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	switch r := r.(type) {
+	case *RuntimeError:
+		*err = r
+	case *ExitError:
+		*err = r
+	case runtimeError:
+		*err = vm.newRuntimeError(string(r))
+	default:
+		*err = vm.newRuntimeError(fmt.Sprintf("internal error: %v", r))
+	}
 }
 
 func (vm *VM) assert(cond bool) {
 	if cond {
 		return
 	}
-	fmt.Printf("[%s] runtime error: ", vm.curProto.filepath)
-	fmt.Printf("call builtin function '%s' failed, ", vm.curCallingBuiltin)
-	fmt.Println("please check count and type of arguments")
-	os.Exit(0)
+	vm.exit("call builtin function '%s' failed, please check count and type of arguments", vm.curCallingBuiltin)
 }